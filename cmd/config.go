@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/tommyalmeida/envsync/internal/config"
+	"github.com/tommyalmeida/envsync/internal/lint"
+	"github.com/tommyalmeida/envsync/internal/output"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the envsync config",
+}
+
+var configCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check the config and its workspaces for mistakes",
+	Long: `Load the config, the files it extends and includes, and the configs of its
+workspaces, and report every problem with its file, line and column:
+unknown keys, values of the wrong kind, unknown variable types, invalid
+patterns, defaults that fail their own variable and rules that do not
+compile.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConfigCheck(newFormatter(cmd))
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configCheckCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigCheck(f *output.Formatter) error {
+	file := viper.ConfigFileUsed()
+
+	if file == "" {
+		return fmt.Errorf("no %s found in this directory or its parents", config.FileName)
+	}
+
+	var problems config.Problems
+	var checked []string
+
+	cfg, err := config.LoadFile(file)
+
+	if err == nil {
+		if checkLintRules(cfg, &problems) {
+			checked = append(checked, config.DisplayPath(file))
+		}
+
+		for _, name := range cfg.Workspaces {
+			workspace, err := cfg.LoadWorkspace(name)
+
+			if err != nil {
+				if !collectProblems(err, &problems) {
+					return err
+				}
+				continue
+			}
+
+			if checkLintRules(workspace.Config, &problems) {
+				checked = append(checked, name)
+			}
+		}
+	} else if !collectProblems(err, &problems) {
+		return err
+	}
+
+	if jsonOutput {
+		if problems == nil {
+			problems = config.Problems{}
+		}
+
+		if err := f.PrintJSON(problems); err != nil {
+			return err
+		}
+	} else {
+		for _, problem := range problems {
+			fmt.Fprintln(f.Stderr(), problem.Error())
+		}
+
+		for _, name := range checked {
+			fmt.Fprintf(f.Stdout(), "✓ %s is valid\n", name)
+		}
+
+		switch len(problems) {
+		case 0:
+		case 1:
+			fmt.Fprintln(f.Stderr(), "✗ 1 problem found")
+		default:
+			fmt.Fprintf(f.Stderr(), "✗ %d problems found\n", len(problems))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ExitError{Code: 1}
+	}
+
+	return nil
+}
+
+// checkLintRules appends a problem for lint rules the linter does not know
+// and reports whether there was none.
+func checkLintRules(cfg *config.Config, problems *config.Problems) bool {
+	if _, err := lint.NewLinter(cfg.Lint.Rules); err != nil {
+		*problems = append(*problems, config.Problem{File: config.DisplayPath(cfg.File), Message: err.Error()})
+		return false
+	}
+
+	return true
+}
+
+// collectProblems appends the problems in err and reports whether it had any.
+func collectProblems(err error, problems *config.Problems) bool {
+	var found config.Problems
+
+	if !errors.As(err, &found) {
+		return false
+	}
+
+	*problems = append(*problems, found...)
+
+	return true
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tommyalmeida/envsync/internal/config"
+	"github.com/tommyalmeida/envsync/internal/env"
+	"github.com/tommyalmeida/envsync/internal/output"
+	"github.com/tommyalmeida/envsync/pkg/schema"
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec --env [env-file] -- [command] [args...]",
+	Short: "Run a command with a validated environment",
+	Long: `Run a command with the variables from one or more env files.
+
+Each --env is an env file, an adapter URI or the name of an environment from
+the config; the schema overrides of the last named environment apply.
+Files are merged in the order given, so later files win, and schema defaults
+are filled in for optional variables that are not set. References resolve
+across all files, and from the process environment with --process-env. The
+result is validated against the schema and the command is only started when
+validation passes. The command inherits the current environment, with the
+merged variables taking precedence.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envFiles, _ := cmd.Flags().GetStringArray("env")
+		return runExec(cmd.Context(), newFormatter(cmd), cmd.InOrStdin(), envFiles, args)
+	},
+}
+
+func init() {
+	execCmd.Flags().StringArrayP("env", "e", nil, "env file, adapter URI or environment to load (repeatable)")
+	_ = execCmd.MarkFlagRequired("env")
+
+	rootCmd.AddCommand(execCmd)
+}
+
+func runExec(ctx context.Context, f *output.Formatter, in io.Reader, envFiles, args []string) error {
+	cfg, err := config.Load()
+
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	loaded := make([]env.Vars, 0, len(envFiles))
+	positions := make(env.Positions)
+	s := cfg.Schema
+
+	for _, envFile := range envFiles {
+		if _, exists := cfg.Environments[envFile]; exists {
+			if s, err = cfg.SchemaFor(envFile); err != nil {
+				return err
+			}
+		}
+
+		vars, err := readRawVars(ctx, cfg, envFile)
+
+		if err != nil {
+			return fmt.Errorf("failed to parse env file: %w", err)
+		}
+
+		loaded = append(loaded, vars)
+
+		// Later files win, also when they are adapters without positions.
+		maps.DeleteFunc(positions, func(key string, _ schema.Position) bool {
+			_, set := vars[key]
+			return set
+		})
+		maps.Copy(positions, readPositions(resolveLocation(cfg, envFile)))
+	}
+
+	// References resolve across all files.
+	merged, err := expandVars(env.Merge(loaded...))
+
+	if err != nil {
+		return err
+	}
+
+	validator, err := newValidator(cfg, s)
+
+	if err != nil {
+		return err
+	}
+
+	envVars, _ := validator.ApplyDefaults(merged)
+
+	if result := validator.WithPositions(positions).Validate(envVars); !result.Valid {
+		if jsonOutput {
+			err = f.PrintJSON(result)
+		} else {
+			err = f.PrintValidationResult(result)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return &ExitError{Code: 1}
+	}
+
+	return runCommand(f, in, args, envVars)
+}
+
+// runCommand runs args with envVars layered over the current environment,
+// connected to in and the streams of f, forwarding signals to the child and
+// exiting with its exit code.
+func runCommand(f *output.Formatter, in io.Reader, args []string, envVars env.Vars) error {
+	child := exec.Command(args[0], args[1:]...)
+	child.Stdin = in
+	child.Stdout = f.Stdout()
+	child.Stderr = f.Stderr()
+	child.Env = childEnviron(envVars)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+
+	if err := child.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", args[0], err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				_ = child.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := child.Wait()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &ExitError{Code: exitCode(exitErr.ProcessState)}
+	}
+
+	return err
+}
+
+func childEnviron(envVars env.Vars) []string {
+	environ := os.Environ()
+	overridden := make(map[string]bool, len(envVars))
+
+	for key := range envVars {
+		overridden[key] = true
+	}
+
+	result := make([]string, 0, len(environ)+len(envVars))
+
+	for _, entry := range environ {
+		key, _, _ := cutEnviron(entry)
+
+		if !overridden[key] {
+			result = append(result, entry)
+		}
+	}
+
+	for _, key := range envVars.Keys() {
+		result = append(result, key+"="+envVars[key])
+	}
+
+	return result
+}
+
+func cutEnviron(entry string) (string, string, bool) {
+	for i := 1; i < len(entry); i++ {
+		if entry[i] == '=' {
+			return entry[:i], entry[i+1:], true
+		}
+	}
+
+	return entry, "", false
+}
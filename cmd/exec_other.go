@@ -0,0 +1,11 @@
+//go:build !unix
+
+package cmd
+
+import "os"
+
+var forwardedSignals = []os.Signal{os.Interrupt}
+
+func exitCode(state *os.ProcessState) int {
+	return state.ExitCode()
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/tommyalmeida/envsync/internal/output"
+)
+
+// setupExec writes a config requiring PORT and an env file with content, and
+// returns the path of the env file.
+func setupExec(t *testing.T, content string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, ".envsync.yaml")
+	envFile := filepath.Join(dir, ".env")
+
+	if err := os.WriteFile(configFile, []byte("schema:\n  variables:\n    PORT:\n      required: true\n      type: port\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(envFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	viper.SetConfigFile(configFile)
+	t.Cleanup(viper.Reset)
+
+	return envFile
+}
+
+func execFormatter() (*output.Formatter, *bytes.Buffer, *bytes.Buffer) {
+	var stdout, stderr bytes.Buffer
+	return output.NewFormatter(&stdout, &stderr, false), &stdout, &stderr
+}
+
+func TestRunExec_ValidationFailure(t *testing.T) {
+	envFile := setupExec(t, "PORT=http\n")
+	marker := filepath.Join(t.TempDir(), "started")
+	f, stdout, _ := execFormatter()
+
+	err := runExec(context.Background(), f, strings.NewReader(""), []string{envFile}, []string{"sh", "-c", "touch " + marker})
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 1 {
+		t.Fatalf("expected exit code 1, got %v", err)
+	}
+
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Error("expected the command not to start")
+	}
+
+	if !strings.Contains(stdout.String(), "Validation failed") {
+		t.Errorf("expected the validation result, got %q", stdout.String())
+	}
+}
+
+func TestRunExec_ExitCode(t *testing.T) {
+	envFile := setupExec(t, "PORT=8080\n")
+	f, stdout, _ := execFormatter()
+
+	err := runExec(context.Background(), f, strings.NewReader(""), []string{envFile}, []string{"sh", "-c", `echo "$PORT"; exit 3`})
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Fatalf("expected exit code 3, got %v", err)
+	}
+
+	if stdout.String() != "8080\n" {
+		t.Errorf("expected the command to see PORT, got %q", stdout.String())
+	}
+
+	if err := runExec(context.Background(), f, strings.NewReader(""), []string{envFile}, []string{"true"}); err != nil {
+		t.Errorf("expected no error for exit code 0, got %v", err)
+	}
+}
+
+func TestRunExec_ProcessEnv(t *testing.T) {
+	envFile := setupExec(t, "PORT=${ENVSYNC_TEST_PORT}\n")
+	t.Setenv("ENVSYNC_TEST_PORT", "9090")
+	t.Cleanup(func() { processEnv = false })
+
+	f, _, _ := execFormatter()
+
+	var exitErr *ExitError
+	if err := runExec(context.Background(), f, strings.NewReader(""), []string{envFile}, []string{"true"}); !errors.As(err, &exitErr) {
+		t.Errorf("expected PORT to be empty without --process-env, got %v", err)
+	}
+
+	processEnv = true
+	f, stdout, _ := execFormatter()
+
+	if err := runExec(context.Background(), f, strings.NewReader(""), []string{envFile}, []string{"sh", "-c", `echo "$PORT"`}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if stdout.String() != "9090\n" {
+		t.Errorf("expected PORT from the process environment, got %q", stdout.String())
+	}
+}
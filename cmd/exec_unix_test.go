@@ -0,0 +1,46 @@
+//go:build unix
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/tommyalmeida/envsync/internal/output"
+)
+
+// readyWriter calls ready once the child has written a line.
+type readyWriter struct {
+	buf   bytes.Buffer
+	once  sync.Once
+	ready func()
+}
+
+func (w *readyWriter) Write(p []byte) (int, error) {
+	n, err := w.buf.Write(p)
+	if bytes.Contains(p, []byte("\n")) {
+		w.once.Do(w.ready)
+	}
+
+	return n, err
+}
+
+func TestRunCommand_ForwardsSignals(t *testing.T) {
+	stdout := &readyWriter{ready: func() {
+		_ = syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+	}}
+
+	f := output.NewFormatter(stdout, &bytes.Buffer{}, false)
+	script := `trap 'exit 7' TERM; echo ready; while :; do sleep 0.1; done`
+
+	err := runCommand(f, strings.NewReader(""), []string{"sh", "-c", script}, nil)
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 7 {
+		t.Fatalf("expected the child to exit with 7 after SIGTERM, got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+
+	"github.com/tommyalmeida/envsync/internal/adapter"
+	"github.com/tommyalmeida/envsync/internal/config"
+	"github.com/tommyalmeida/envsync/internal/env"
+	"github.com/tommyalmeida/envsync/internal/format"
+	"github.com/tommyalmeida/envsync/internal/output"
+)
+
+var fmtCmd = &cobra.Command{
+	Use:   "fmt [env-file...]",
+	Short: "Rewrite env files in canonical form",
+	Long: `Rewrite env files in canonical form: schema variables first in the order
+the config declares them, the other variables grouped by prefix and sorted,
+comments kept above the variable they describe, values quoted only when they
+need it and inline comments aligned. Values never change.
+
+Each argument may be the name of an environment from the config. Without
+arguments the files of all configured environments are formatted, or .env
+when there are none.
+
+With --check nothing is written; the files that are not formatted are listed
+and the command fails. With --diff nothing is written either and the changes
+are printed as a unified diff.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		check, _ := cmd.Flags().GetBool("check")
+		diff, _ := cmd.Flags().GetBool("diff")
+		return runFmt(newFormatter(cmd), args, check, diff)
+	},
+}
+
+func init() {
+	fmtCmd.Flags().Bool("check", false, "list files that are not formatted and fail instead of writing them")
+	fmtCmd.Flags().Bool("diff", false, "print the changes as a unified diff instead of writing them")
+
+	rootCmd.AddCommand(fmtCmd)
+}
+
+func runFmt(f *output.Formatter, locations []string, check, showDiff bool) error {
+	cfg, err := config.Load()
+
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if len(locations) == 0 {
+		locations = envFileLocations(cfg)
+	}
+
+	unformatted := 0
+
+	for _, location := range locations {
+		file := resolveLocation(cfg, location)
+
+		if adapter.IsURI(file) {
+			return fmt.Errorf("cannot format %s, only local env files can be formatted", location)
+		}
+
+		doc, err := env.ParseDocumentFile(file)
+
+		if err != nil {
+			return err
+		}
+
+		name := config.DisplayPath(file)
+		formatted, err := format.Format(doc, cfg.VariableOrder)
+
+		if err != nil {
+			return fmt.Errorf("failed to format %s: %w", name, err)
+		}
+
+		original, result := doc.String(), formatted.String()
+
+		if original == result {
+			continue
+		}
+
+		unformatted++
+
+		switch {
+		case showDiff:
+			diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        diffLines(original),
+				B:        diffLines(result),
+				FromFile: name + ".orig",
+				ToFile:   name,
+				Context:  3,
+			})
+
+			if err != nil {
+				return fmt.Errorf("failed to diff %s: %w", name, err)
+			}
+
+			if _, err := io.WriteString(f.Stdout(), diff); err != nil {
+				return fmt.Errorf("failed to write diff of %s: %w", name, err)
+			}
+		case check:
+			fmt.Fprintln(f.Stdout(), name)
+		default:
+			if err := formatted.WriteToFile(file); err != nil {
+				return fmt.Errorf("failed to write %s: %w", name, err)
+			}
+
+			fmt.Fprintf(f.Stderr(), "Formatted %s\n", name)
+		}
+	}
+
+	if check && unformatted > 0 {
+		return &ExitError{Code: 1}
+	}
+
+	return nil
+}
+
+// diffLines splits text into lines that keep their line ending, without the
+// empty line difflib.SplitLines adds after the last one.
+func diffLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
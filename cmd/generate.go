@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tommyalmeida/envsync/internal/config"
+	"github.com/tommyalmeida/envsync/internal/generate"
+	"github.com/tommyalmeida/envsync/internal/output"
+	"github.com/tommyalmeida/envsync/pkg/schema"
+)
+
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate files from the schema",
+}
+
+var generateExampleCmd = &cobra.Command{
+	Use:   "example",
+	Short: "Write a commented .env.example from the schema",
+	Long: `Write an env file with every schema variable, its description and type as
+comments and its default or a placeholder as the value. Secret defaults are
+replaced by placeholders.
+
+With --check nothing is written and the command fails when the file is out
+of date.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runGenerate(cmd, ".env.example", func(s schema.Schema) (string, error) {
+			return generate.Example(s), nil
+		})
+	},
+}
+
+var generateDocsCmd = &cobra.Command{
+	Use:   "docs",
+	Short: "Write a reference table of the schema variables",
+	Long: `Write a Markdown or HTML table of the schema variables with their type,
+constraints, default and description.
+
+With --check nothing is written and the command fails when the file is out
+of date.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+
+		defaultOutput := "ENVIRONMENT.md"
+		if format == generate.FormatHTML {
+			defaultOutput = "ENVIRONMENT.html"
+		}
+
+		return runGenerate(cmd, defaultOutput, func(s schema.Schema) (string, error) {
+			return generate.Docs(s, format)
+		})
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{generateExampleCmd, generateDocsCmd} {
+		c.Flags().StringP("output", "o", "", "file to write, - for stdout")
+		c.Flags().String("env", "", "environment whose schema overrides apply")
+		c.Flags().Bool("check", false, "fail if the file is not up to date instead of writing it")
+	}
+
+	generateDocsCmd.Flags().String("format", generate.FormatMarkdown, "markdown or html")
+
+	generateCmd.AddCommand(generateExampleCmd)
+	generateCmd.AddCommand(generateDocsCmd)
+	rootCmd.AddCommand(generateCmd)
+}
+
+func runGenerate(cmd *cobra.Command, defaultOutput string, render func(schema.Schema) (string, error)) error {
+	outputFile, _ := cmd.Flags().GetString("output")
+	envName, _ := cmd.Flags().GetString("env")
+	check, _ := cmd.Flags().GetBool("check")
+
+	if outputFile == "" {
+		outputFile = defaultOutput
+	}
+
+	cfg, err := config.Load()
+
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	f := newFormatter(cmd)
+	s := cfg.Schema
+
+	if envName != "" {
+		if s, err = cfg.SchemaFor(envName); err != nil {
+			return err
+		}
+	}
+
+	content, err := render(s)
+
+	if err != nil {
+		return err
+	}
+
+	if outputFile == "-" {
+		if check {
+			return fmt.Errorf("--check needs a file to compare, not stdout")
+		}
+
+		_, err := io.WriteString(f.Stdout(), content)
+		return err
+	}
+
+	if check {
+		return checkGenerated(cmd, f, outputFile, content)
+	}
+
+	if err := os.WriteFile(outputFile, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", outputFile, err)
+	}
+
+	fmt.Fprintf(f.Stdout(), "Wrote %s\n", outputFile)
+
+	return nil
+}
+
+func checkGenerated(cmd *cobra.Command, f *output.Formatter, filename, content string) error {
+	existing, err := os.ReadFile(filename)
+
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+
+	if bytes.Equal(existing, []byte(content)) {
+		fmt.Fprintf(f.Stdout(), "✓ %s is up to date\n", filename)
+		return nil
+	}
+
+	fmt.Fprintf(f.Stderr(), "✗ %s is out of date, run `envsync %s %s` to update it\n",
+		filename, cmd.Parent().Name(), cmd.Name())
+
+	return &ExitError{Code: 1}
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tommyalmeida/envsync/internal/config"
+	"github.com/tommyalmeida/envsync/internal/env"
+	"github.com/tommyalmeida/envsync/internal/output"
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph [env-file...]",
+	Short: "Show which variables reference which",
+	Long: `Show the ${VAR} references between variables.
+
+The files are merged in order before references are resolved, and the command
+fails when the references form a cycle or a ${VAR:?error} reference is unset.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runGraph(cmd.Context(), newFormatter(cmd), args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+}
+
+type graphResult struct {
+	References env.DependencyGraph `json:"references"`
+	Undefined  []string            `json:"undefined,omitempty"`
+}
+
+func runGraph(ctx context.Context, f *output.Formatter, locations []string) error {
+	cfg, err := config.Load()
+
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	loaded := make([]env.Vars, 0, len(locations))
+
+	for _, location := range locations {
+		vars, err := readRawVars(ctx, cfg, location)
+
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", location, err)
+		}
+
+		loaded = append(loaded, vars)
+	}
+
+	raw := env.Merge(loaded...)
+
+	graph, err := env.References(raw)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := expandVars(raw); err != nil {
+		return err
+	}
+
+	result := graphResult{References: graph}
+	undefined := make(map[string]bool)
+
+	for _, names := range graph {
+		for _, name := range names {
+			if _, exists := raw[name]; !exists {
+				undefined[name] = true
+			}
+		}
+	}
+
+	result.Undefined = slices.Sorted(maps.Keys(undefined))
+
+	if jsonOutput {
+		return f.PrintJSON(result)
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(graph)) {
+		fmt.Fprintf(f.Stdout(), "%s -> %s\n", key, strings.Join(graph[key], ", "))
+	}
+
+	if len(result.Undefined) > 0 {
+		fmt.Fprintf(f.Stdout(), "\nUndefined references: %s\n", strings.Join(result.Undefined, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tommyalmeida/envsync/internal/adapter"
+	"github.com/tommyalmeida/envsync/internal/config"
+	"github.com/tommyalmeida/envsync/internal/env"
+	"github.com/tommyalmeida/envsync/internal/lint"
+	"github.com/tommyalmeida/envsync/internal/output"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [env-file...]",
+	Short: "Check env files for duplicate keys, stray whitespace and other mistakes",
+	Long: `Check env files for mistakes that most parsers accept silently: lines that
+are not assignments, duplicate keys, keys that are not upper case or that
+shells cannot export, trailing whitespace, unquoted values containing # and
+mixed export prefixes.
+
+Each argument may be the name of an environment from the config. Without
+arguments the files of all configured environments are linted, or .env when
+there are none. The severity of every rule can be set to error, warn or off
+under lint.rules in the config; only errors make the command fail.
+
+With --fix the files are rewritten first: overridden assignments and
+trailing whitespace are removed, values containing # are quoted and export
+prefixes are made consistent. Fixes never change a value, so keys that need
+renaming are only reported; envsync fmt rewrites files in canonical form.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fix, _ := cmd.Flags().GetBool("fix")
+
+		if err := checkFormat(); err != nil {
+			return err
+		}
+
+		return runLint(newFormatter(cmd), args, fix)
+	},
+}
+
+func init() {
+	lintCmd.Flags().Bool("fix", false, "fix what the fixable rules report")
+	addFormatFlag(lintCmd)
+
+	rootCmd.AddCommand(lintCmd)
+}
+
+func runLint(f *output.Formatter, locations []string, fix bool) error {
+	cfg, err := config.Load()
+
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	linter, err := lint.NewLinter(cfg.Lint.Rules)
+
+	if err != nil {
+		return err
+	}
+
+	if len(locations) == 0 {
+		locations = envFileLocations(cfg)
+	}
+
+	findings := []lint.Finding{}
+	var reports []output.Report
+
+	for _, location := range locations {
+		file := resolveLocation(cfg, location)
+
+		if adapter.IsURI(file) {
+			return fmt.Errorf("cannot lint %s, only local env files can be linted", location)
+		}
+
+		doc, err := env.ParseDocumentFile(file)
+
+		if err != nil {
+			return err
+		}
+
+		doc.File = config.DisplayPath(file)
+
+		if fix {
+			fixed, err := linter.Fix(doc)
+
+			if err != nil {
+				return err
+			}
+
+			if fixed.String() != doc.String() {
+				if err := fixed.WriteToFile(file); err != nil {
+					return fmt.Errorf("failed to write %s: %w", file, err)
+				}
+
+				fmt.Fprintf(f.Stderr(), "Fixed %s\n", doc.File)
+			}
+
+			doc = fixed
+		}
+
+		docFindings := linter.Lint(doc)
+		findings = append(findings, docFindings...)
+		reports = append(reports, output.LintReport(doc.File, doc.Keys(), docFindings))
+	}
+
+	switch {
+	case ciFormat():
+		err = f.PrintReports(reportFormat, reports...)
+	case jsonOutput:
+		err = f.PrintJSON(findings)
+	default:
+		err = f.PrintLintFindings(findings)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if lint.HasErrors(findings) {
+		return &ExitError{Code: 1}
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tommyalmeida/envsync/internal/adapter"
+	"github.com/tommyalmeida/envsync/internal/config"
+	"github.com/tommyalmeida/envsync/internal/env"
+	"github.com/tommyalmeida/envsync/internal/output"
+)
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge [base-env] [ours-env] [theirs-env]",
+	Short: "Merge two edits of an env file using their common base",
+	Long: `Merge the changes ours and theirs made to base.
+
+Changes made on one side are applied, including deletions, and so are
+identical changes made on both sides. Variables both sides changed
+differently are written as conflict markers and the command exits with
+status 1. The result is written to the ours file unless --output is given;
+use --output - to print it instead. Comments, ordering and quoting of the
+ours file are kept.
+
+To use envsync as a git merge driver for .env.example files:
+
+  git config merge.envsync.name "envsync three-way merge"
+  git config merge.envsync.driver "envsync merge %O %A %B"
+  echo ".env.example merge=envsync" >> .gitattributes`,
+	Args: cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputFile, _ := cmd.Flags().GetString("output")
+		return runMerge(newFormatter(cmd), args[0], args[1], args[2], outputFile)
+	},
+}
+
+func init() {
+	mergeCmd.Flags().StringP("output", "o", "", "file to write the result to, - for stdout (default is the ours file)")
+
+	rootCmd.AddCommand(mergeCmd)
+}
+
+func runMerge(f *output.Formatter, baseFile, oursFile, theirsFile, outputFile string) error {
+	if jsonOutput && outputFile == "-" {
+		return fmt.Errorf("--json cannot be combined with --output -")
+	}
+
+	cfg, err := config.Load()
+
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	docs := make([]*env.Document, 0, 3)
+
+	for _, location := range []string{baseFile, oursFile, theirsFile} {
+		location = resolveLocation(cfg, location)
+
+		if adapter.IsURI(location) {
+			return fmt.Errorf("cannot merge %s: merge only works on env files", location)
+		}
+
+		doc, err := env.ParseDocumentFile(location)
+
+		if err != nil {
+			return err
+		}
+
+		docs = append(docs, doc)
+	}
+
+	merged, result := env.MergeDocuments(docs[0], docs[1], docs[2])
+
+	if outputFile == "" {
+		outputFile = resolveLocation(cfg, oursFile)
+	}
+
+	destination := outputFile
+
+	if outputFile == "-" {
+		destination = "stdout"
+
+		if _, err := io.WriteString(f.Stdout(), merged.String()); err != nil {
+			return fmt.Errorf("failed to write merge result: %w", err)
+		}
+	} else if err := merged.WriteToFile(outputFile); err != nil {
+		return fmt.Errorf("failed to write merge result: %w", err)
+	}
+
+	masker, err := env.NewMasker(cfg)
+
+	if err != nil {
+		return fmt.Errorf("failed to set up secret masking: %w", err)
+	}
+
+	result = masker.MaskMerge(result)
+
+	if jsonOutput {
+		err = f.PrintJSON(result)
+	} else {
+		err = f.PrintMergeResult(result, destination)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if len(result.Conflicts) > 0 {
+		return &ExitError{Code: 1}
+	}
+
+	return nil
+}
@@ -1,55 +1,120 @@
 package cmd
 
 import (
-	"encoding/json"
+	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"log"
-	"os"
+	"log/slog"
+	"slices"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/tommyalmeida/envsync/internal/adapter"
 	"github.com/tommyalmeida/envsync/internal/config"
 	"github.com/tommyalmeida/envsync/internal/env"
 	"github.com/tommyalmeida/envsync/internal/output"
+	"github.com/tommyalmeida/envsync/pkg/schema"
 )
 
 var (
-	cfgFile    string
-	jsonOutput bool
-	verbose    bool
+	cfgFile      string
+	jsonOutput   bool
+	reportFormat string
+	verbose      bool
+	processEnv   bool
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "envsync",
 	Short: "Keep environment variable files consistent across environments",
+	// Commands return an ExitError for failed checks, which is not a usage
+	// mistake, and main prints every other error.
+	SilenceUsage:  true,
+	SilenceErrors: true,
 }
 
 var validateCmd = &cobra.Command{
 	Use:   "validate [env-file]",
 	Short: "Validate an environment file against a schema",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(_ *cobra.Command, args []string) error {
-		return runValidate(args[0])
+	Long: `Validate an environment file against a schema.
+
+The file may be an adapter URI or the name of an environment from the
+environments section, in which case that environment's schema overrides
+apply. Use --env to validate against an environment's schema and --all to
+validate every configured environment, including those of the projects in
+the workspaces section.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envName, _ := cmd.Flags().GetString("env")
+		all, _ := cmd.Flags().GetBool("all")
+
+		if err := checkFormat(); err != nil {
+			return err
+		}
+
+		var envFile string
+		if len(args) > 0 {
+			envFile = args[0]
+		}
+
+		return runValidate(cmd.Context(), newFormatter(cmd), envFile, envName, all)
 	},
 }
 
 var diffCmd = &cobra.Command{
-	Use:   "diff [source-env] [target-env]",
-	Short: "Compare two environment files and show differences",
-	Args:  cobra.ExactArgs(2),
-	RunE: func(_ *cobra.Command, args []string) error {
-		return runDiff(args[0], args[1])
+	Use:   "diff [source-env] [target-env] [more-envs...]",
+	Short: "Compare environment files and show differences",
+	Long: `Compare environment files and show differences.
+
+Each argument may be an adapter URI such as ssm://app/prod or the name of an
+environment from the config instead of a file. With more than two arguments,
+or with --matrix, a key × environment matrix is shown instead.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		matrix, _ := cmd.Flags().GetBool("matrix")
+		raw, _ := cmd.Flags().GetBool("raw")
+
+		if err := checkFormat(); err != nil {
+			return err
+		}
+
+		if matrix || len(args) > 2 {
+			return runDiffMatrix(cmd.Context(), newFormatter(cmd), args, raw)
+		}
+
+		return runDiff(cmd.Context(), newFormatter(cmd), args[0], args[1], raw)
 	},
 }
 
 var syncCmd = &cobra.Command{
 	Use:   "sync [source-env] [target-env]",
 	Short: "Synchronize missing variables from source to target",
-	Args:  cobra.ExactArgs(2),
+	Long: `Synchronize missing variables from source to target.
+
+Either side may be an adapter URI such as ssm://app/prod or the name of an
+environment from the config instead of a file.
+
+Strategies:
+  add-only     add variables missing from the target (default)
+  overwrite    also copy source values that differ
+  prune        also remove target variables that are in neither the source
+               nor the schema
+  mirror       add, overwrite and prune
+  interactive  add missing variables and ask about every differing value`,
+	Args: cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		dryRun, _ := cmd.Flags().GetBool("dry-run")
-		return runSync(args[0], args[1], dryRun)
+		strategy, _ := cmd.Flags().GetString("strategy")
+
+		if err := checkFormat(); err != nil {
+			return err
+		}
+
+		return runSync(cmd.Context(), newFormatter(cmd), cmd.InOrStdin(), args[0], args[1], strategy, dryRun)
 	},
 }
 
@@ -59,8 +124,20 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is .envsync.yaml)")
 	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "output in JSON format")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().BoolVar(&processEnv, "process-env", false, "resolve ${VAR} references from the process environment too")
+
+	validateCmd.Flags().String("env", "", "environment from the config to validate against")
+	validateCmd.Flags().Bool("all", false, "validate every configured environment and workspace")
+
+	diffCmd.Flags().Bool("matrix", false, "show a key × environment matrix even for two files")
+	diffCmd.Flags().Bool("raw", false, "compare values before ${VAR} references are expanded")
 
 	syncCmd.Flags().Bool("dry-run", false, "show what would be synced without making changes")
+	syncCmd.Flags().String("strategy", string(env.StrategyAddOnly), "add-only, overwrite, prune, mirror or interactive")
+
+	for _, c := range []*cobra.Command{validateCmd, diffCmd, syncCmd} {
+		addFormatFlag(c)
+	}
 
 	rootCmd.AddCommand(validateCmd)
 	rootCmd.AddCommand(diffCmd)
@@ -68,13 +145,23 @@ func init() {
 }
 
 func initConfig() {
+	if verbose {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+	}
+
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
 	} else {
-		viper.SetConfigName(".envsync")
-		viper.SetConfigType("yaml")
+		found, err := config.Find(".")
 
-		viper.AddConfigPath(".")
+		if err != nil || found == "" {
+			if verbose {
+				log.Printf("Warning: No %s found in this directory or its parents\n", config.FileName)
+			}
+			return
+		}
+
+		viper.SetConfigFile(found)
 	}
 
 	if err := viper.ReadInConfig(); err != nil {
@@ -84,40 +171,113 @@ func initConfig() {
 	}
 }
 
-func runDiff(sourceFile, targetFile string) error {
-	sourceVars, err := env.ParseFile(sourceFile)
+func runDiff(ctx context.Context, f *output.Formatter, sourceFile, targetFile string, raw bool) error {
+	cfg, err := config.Load()
+
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	sourceVars, err := readDiffVars(ctx, cfg, sourceFile, raw)
 
 	if err != nil {
 		return fmt.Errorf("failed to parse source file: %w", err)
 	}
 
-	targetVars, err := env.ParseFile(targetFile)
+	targetVars, err := readDiffVars(ctx, cfg, targetFile, raw)
 
 	if err != nil {
 		return fmt.Errorf("failed to parse target file: %w", err)
 	}
 
-	diff := env.CompareEnvs(sourceVars, targetVars)
+	rules, err := env.NewRuleSet(cfg.Rules)
+
+	if err != nil {
+		return fmt.Errorf("failed to load rules: %w", err)
+	}
+
+	masker, err := env.NewMasker(cfg)
+
+	if err != nil {
+		return fmt.Errorf("failed to set up secret masking: %w", err)
+	}
+
+	diff := masker.MaskDiff(env.CompareEnvsWithRules(sourceVars, targetVars, rules.WithSchema(cfg.Schema)))
+
+	if ciFormat() {
+		target := resolveLocation(cfg, targetFile)
+		return f.PrintReports(reportFormat, output.DiffReport(config.DisplayPath(resolveLocation(cfg, sourceFile)), config.DisplayPath(target), diff, readPositions(target)))
+	}
 
 	if jsonOutput {
-		return outputJSON(diff)
+		return f.PrintJSON(diff)
+	}
+
+	return f.PrintDiff(diff, sourceFile, targetFile)
+}
+
+// readDiffVars reads expanded values, or the raw values when comparing
+// references rather than what they resolve to.
+func readDiffVars(ctx context.Context, cfg *config.Config, location string, raw bool) (env.Vars, error) {
+	if raw {
+		return readRawVars(ctx, cfg, location)
 	}
 
-	formatter := output.NewFormatter(!jsonOutput)
-	return formatter.PrintDiff(diff, sourceFile, targetFile)
+	return readVars(ctx, cfg, location)
 }
 
-func runSync(sourceFile, targetFile string, dryRun bool) error {
-	sourceVars, err := env.ParseFile(sourceFile)
+func runDiffMatrix(ctx context.Context, f *output.Formatter, locations []string, raw bool) error {
+	cfg, err := config.Load()
 
 	if err != nil {
-		return fmt.Errorf("failed to parse source file: %w", err)
+		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	targetVars, err := env.ParseFile(targetFile)
+	rules, err := env.NewRuleSet(cfg.Rules)
 
 	if err != nil {
-		return fmt.Errorf("failed to parse target file: %w", err)
+		return fmt.Errorf("failed to load rules: %w", err)
+	}
+
+	envs := make([]env.Vars, 0, len(locations))
+
+	for _, location := range locations {
+		vars, err := readDiffVars(ctx, cfg, location, raw)
+
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", location, err)
+		}
+
+		envs = append(envs, vars)
+	}
+
+	matrix := env.CompareManyWithRules(locations, envs, rules)
+
+	if ciFormat() {
+		files := make([]string, len(locations))
+		positions := make([]env.Positions, len(locations))
+
+		for i, location := range locations {
+			file := resolveLocation(cfg, location)
+			files[i] = config.DisplayPath(file)
+			positions[i] = readPositions(file)
+		}
+
+		return f.PrintReports(reportFormat, output.MatrixReport(files, matrix, positions)...)
+	}
+
+	if jsonOutput {
+		return f.PrintJSON(matrix)
+	}
+
+	return f.PrintMatrix(matrix, verbose)
+}
+
+func runSync(ctx context.Context, f *output.Formatter, in io.Reader, sourceFile, targetFile, strategyName string, dryRun bool) error {
+	strategy, err := env.ParseStrategy(strategyName)
+
+	if err != nil {
+		return err
 	}
 
 	cfg, err := config.Load()
@@ -126,49 +286,448 @@ func runSync(sourceFile, targetFile string, dryRun bool) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	syncer := env.NewSyncer(cfg)
-	result, err := syncer.Sync(sourceVars, targetVars, targetFile, dryRun)
+	// Values are compared expanded but copied as written in the source, so
+	// that references are kept and never resolved into the target.
+	sourceTemplates, err := readRawVars(ctx, cfg, sourceFile)
+
+	if err != nil {
+		return fmt.Errorf("failed to parse source file: %w", err)
+	}
+
+	sourceVars, err := expandVars(sourceTemplates)
+
+	if err != nil {
+		return fmt.Errorf("failed to parse source file: %w", err)
+	}
+
+	targetVars, err := readVars(ctx, cfg, targetFile)
+
+	if err != nil {
+		return fmt.Errorf("failed to parse target file: %w", err)
+	}
+
+	rules, err := env.NewRuleSet(cfg.Rules)
+
+	if err != nil {
+		return fmt.Errorf("failed to load rules: %w", err)
+	}
+
+	dest, err := destination(ctx, cfg, targetFile)
+
+	if err != nil {
+		return err
+	}
+
+	masker, err := env.NewMasker(cfg)
+
+	if err != nil {
+		return fmt.Errorf("failed to set up secret masking: %w", err)
+	}
+
+	syncer := env.NewSyncer(cfg).
+		WithRules(rules).
+		WithStrategy(strategy, promptResolver(f, in, masker)).
+		WithTemplates(sourceTemplates)
+	result, err := syncer.SyncTo(sourceVars, targetVars, dest, dryRun)
 
 	if err != nil {
 		return fmt.Errorf("failed to sync: %w", err)
 	}
 
+	if ciFormat() {
+		return f.PrintReports(reportFormat, output.SyncReport(result, readPositions(resolveLocation(cfg, targetFile))))
+	}
+
 	if jsonOutput {
-		return outputJSON(result)
+		return f.PrintJSON(result)
 	}
 
-	formatter := output.NewFormatter(!jsonOutput)
-	return formatter.PrintSyncResult(result, dryRun)
+	return f.PrintSyncResult(result, dryRun)
 }
 
-func runValidate(envFile string) error {
+// promptResolver asks on stderr whether to overwrite each conflicting value
+// and reads the answers from in. Secret values are shown masked.
+func promptResolver(f *output.Formatter, in io.Reader, masker *env.Masker) env.Resolver {
+	reader := bufio.NewReader(in)
+
+	return func(key, sourceValue, targetValue string) (bool, error) {
+		fmt.Fprintf(f.Stderr(), "%s differs:\n  source: %s\n  target: %s\nUse the source value? [y/N] ",
+			key, masker.Mask(key, sourceValue), masker.Mask(key, targetValue))
+
+		answer, err := reader.ReadString('\n')
+
+		if err != nil && (err != io.EOF || answer == "") {
+			return false, fmt.Errorf("failed to read answer for %s: %w", key, err)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return true, nil
+		default:
+			return false, nil
+		}
+	}
+}
+
+func runValidate(ctx context.Context, f *output.Formatter, envFile, envName string, all bool) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	envVars, err := env.ParseFile(envFile)
+	if verbose && reportFormat == output.FormatText && !jsonOutput {
+		f.PrintProvenance(cfg.Provenance)
+	}
+
+	if all {
+		return runValidateAll(ctx, f, cfg)
+	}
+
+	if envName == "" {
+		if _, exists := cfg.Environments[envFile]; exists {
+			envName = envFile
+		}
+	}
+
+	s := cfg.Schema
+
+	if envName != "" {
+		environment, err := cfg.Environment(envName)
+
+		if err != nil {
+			return err
+		}
+
+		if envFile == "" {
+			envFile = cfg.ResolvePath(environment.Path)
+		}
+
+		if s, err = cfg.SchemaFor(envName); err != nil {
+			return err
+		}
+	}
+
+	if envFile == "" {
+		return fmt.Errorf("an env file, --env or --all is required")
+	}
+
+	envVars, err := readVars(ctx, cfg, envFile)
 
 	if err != nil {
 		return fmt.Errorf("failed to parse env file: %w", err)
 	}
 
-	validator := env.NewValidator(cfg.Schema)
-	result := validator.Validate(envVars)
+	validator, err := newValidator(cfg, s)
 
-	if jsonOutput {
-		return outputJSON(result)
+	if err != nil {
+		return err
+	}
+
+	location := resolveLocation(cfg, envFile)
+	positions := readPositions(location)
+	result := validator.WithPositions(positions).Validate(envVars)
+
+	switch {
+	case ciFormat():
+		err = f.PrintReports(reportFormat, output.ValidationReport(config.DisplayPath(location), result, checkedVariables(s, envVars, result), positions))
+	case jsonOutput:
+		err = f.PrintJSON(result)
+	default:
+		err = f.PrintValidationResult(result)
+	}
+
+	if err != nil {
+		return err
 	}
 
-	formatter := output.NewFormatter(!jsonOutput)
-	return formatter.PrintValidationResult(result)
+	if !result.Valid {
+		return &ExitError{Code: 1}
+	}
+
+	return nil
 }
 
-func outputJSON(v any) error {
-	encoder := json.NewEncoder(os.Stdout)
-	encoder.SetIndent("", "  ")
+// runValidateAll validates every environment of cfg and of its workspaces.
+// Workspace results are named "workspace (environment)"; a workspace without
+// environments is validated against the .env file next to its config.
+func runValidateAll(ctx context.Context, f *output.Formatter, cfg *config.Config) error {
+	workspaces, err := cfg.LoadWorkspaces()
+
+	if err != nil {
+		return err
+	}
+
+	if len(cfg.Environments) == 0 && len(workspaces) == 0 {
+		return fmt.Errorf("no environments configured")
+	}
+
+	var names []string
+	var reports []output.Report
+	results := make(map[string]env.ValidationResult)
+
+	add := func(name string, cfg *config.Config, s schema.Schema, location string) error {
+		envVars, err := readVars(ctx, cfg, location)
+
+		if err != nil {
+			return fmt.Errorf("failed to parse env file for %s: %w", name, err)
+		}
+
+		validator, err := newValidator(cfg, s)
+
+		if err != nil {
+			return err
+		}
+
+		file := resolveLocation(cfg, location)
+		positions := readPositions(file)
+		result := validator.WithPositions(positions).Validate(envVars)
+		names = append(names, name)
+		results[name] = result
+
+		report := output.ValidationReport(config.DisplayPath(file), result, checkedVariables(s, envVars, result), positions)
+		report.Name = "validate " + name
+		reports = append(reports, report)
+
+		return nil
+	}
+
+	for _, name := range cfg.EnvironmentNames() {
+		s, err := cfg.SchemaFor(name)
+
+		if err != nil {
+			return err
+		}
+
+		if err := add(name, cfg, s, name); err != nil {
+			return err
+		}
+	}
+
+	for _, workspace := range workspaces {
+		wsCfg := workspace.Config
+
+		if len(wsCfg.Environments) == 0 {
+			if err := add(workspace.Name, wsCfg, wsCfg.Schema, wsCfg.ResolvePath(".env")); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, name := range wsCfg.EnvironmentNames() {
+			s, err := wsCfg.SchemaFor(name)
+
+			if err != nil {
+				return err
+			}
+
+			if err := add(workspace.Name+" ("+name+")", wsCfg, s, name); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch {
+	case ciFormat():
+		err = f.PrintReports(reportFormat, reports...)
+	case jsonOutput:
+		err = f.PrintJSON(results)
+	default:
+		err = f.PrintValidationResults(names, results)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		if !result.Valid {
+			return &ExitError{Code: 1}
+		}
+	}
+
+	return nil
+}
+
+func newValidator(cfg *config.Config, s schema.Schema) (*env.Validator, error) {
+	rules, err := env.NewRuleSet(cfg.Rules)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rules: %w", err)
+	}
+
+	return env.NewValidator(s).WithRules(rules), nil
+}
+
+// checkedVariables lists the variables a validation report has a check for:
+// those of the schema and of the file, without ignored ones.
+func checkedVariables(s schema.Schema, envVars env.Vars, result env.ValidationResult) []string {
+	var names []string
+
+	for name := range s.Variables {
+		names = append(names, name)
+	}
+
+	for name := range envVars {
+		if !slices.Contains(result.Ignored, name) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// readPositions locates the variables of the env file at location, or
+// returns nil for adapter URIs and files that cannot be read.
+func readPositions(location string) env.Positions {
+	if adapter.IsURI(location) {
+		return nil
+	}
+
+	doc, err := env.ParseDocumentFile(location)
+
+	if err != nil {
+		return nil
+	}
+
+	doc.File = config.DisplayPath(location)
+
+	return doc.Positions()
+}
+
+// resolveLocation maps the name of a configured environment to its path and
+// returns any other location unchanged.
+func resolveLocation(cfg *config.Config, location string) string {
+	if environment, exists := cfg.Environments[location]; exists {
+		return cfg.ResolvePath(environment.Path)
+	}
+
+	return location
+}
+
+// envFileLocations returns the local files of the configured environments, or
+// .env next to the config when there are none.
+func envFileLocations(cfg *config.Config) []string {
+	var locations []string
+
+	for _, name := range cfg.EnvironmentNames() {
+		if !adapter.IsURI(resolveLocation(cfg, name)) {
+			locations = append(locations, name)
+		}
+	}
+
+	if len(locations) == 0 {
+		locations = append(locations, cfg.ResolvePath(".env"))
+	}
+
+	return locations
+}
+
+// readVars reads variables from an env file, a configured environment or,
+// for adapter URIs such as ssm://app/prod, from the adapter, and expands
+// their references.
+func readVars(ctx context.Context, cfg *config.Config, location string) (env.Vars, error) {
+	raw, err := readRawVars(ctx, cfg, location)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return expandVars(raw)
+}
+
+// readRawVars is readVars without expansion. Values read from adapters are
+// escaped so that they are never expanded.
+func readRawVars(ctx context.Context, cfg *config.Config, location string) (env.Vars, error) {
+	location = resolveLocation(cfg, location)
+
+	if !adapter.IsURI(location) {
+		return env.ParseFileRaw(location)
+	}
+
+	a, path, err := adapter.Open(location, cfg.Adapter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	vars, err := a.Read(ctx, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return env.EscapeTemplates(vars), nil
+}
+
+func expandVars(raw env.Vars) (env.Vars, error) {
+	vars, err := env.Expand(raw, env.ExpandOptions{ProcessEnv: processEnv})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand variables: %w", err)
+	}
+
+	return vars, nil
+}
+
+func destination(ctx context.Context, cfg *config.Config, location string) (env.Destination, error) {
+	location = resolveLocation(cfg, location)
+
+	if !adapter.IsURI(location) {
+		return env.FileDestination(location), nil
+	}
+
+	a, path, err := adapter.Open(location, cfg.Adapter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return adapter.Destination(ctx, a, location, path), nil
+}
+
+// addFormatFlag adds the --format flag shared by the commands that write
+// reports.
+func addFormatFlag(c *cobra.Command) {
+	c.Flags().StringVar(&reportFormat, "format", output.FormatText,
+		"output format: "+strings.Join(output.Formats, ", "))
+}
+
+// checkFormat validates --format. json is the same as --json.
+func checkFormat() error {
+	if !slices.Contains(output.Formats, reportFormat) {
+		return fmt.Errorf("unknown format %q, expected one of %s", reportFormat, strings.Join(output.Formats, ", "))
+	}
+
+	if ciFormat() && jsonOutput {
+		return fmt.Errorf("--json cannot be combined with --format %s", reportFormat)
+	}
+
+	if reportFormat == output.FormatJSON {
+		jsonOutput = true
+	}
+
+	return nil
+}
+
+// ciFormat reports whether --format asks for one of the CI report formats.
+func ciFormat() bool {
+	return reportFormat != output.FormatText && reportFormat != output.FormatJSON
+}
+
+// newFormatter prints to the output streams of cmd, in colour unless the
+// output is JSON.
+func newFormatter(cmd *cobra.Command) *output.Formatter {
+	return output.NewFormatter(cmd.OutOrStdout(), cmd.ErrOrStderr(), !jsonOutput)
+}
+
+// ExitError makes the process exit with Code. Commands return it once they
+// have already reported the failure themselves.
+type ExitError struct {
+	Code int
+}
 
-	return encoder.Encode(v)
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
 }
 
 func Execute() error {
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tommyalmeida/envsync/internal/config"
+	"github.com/tommyalmeida/envsync/internal/env"
+	"github.com/tommyalmeida/envsync/internal/output"
+	"github.com/tommyalmeida/envsync/pkg/schema"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Work with the config schema",
+}
+
+var schemaInferCmd = &cobra.Command{
+	Use:   "infer [env-file...]",
+	Short: "Guess a schema from existing env files",
+	Long: `Guess a schema from existing env files.
+
+Variables set to a non-empty value in every file are required, types are the
+most specific type all values satisfy, and variables are secret when their
+name matches a secret pattern or a value looks like a random token. The
+schema is printed as a config block; with --merge the variables the config
+file does not define yet are added to it instead.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		merge, _ := cmd.Flags().GetBool("merge")
+		return runSchemaInfer(cmd.Context(), newFormatter(cmd), args, merge)
+	},
+}
+
+var schemaExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the schema for other tools",
+	Long: `Export the schema as a JSON Schema draft 2020-12 document describing an
+object of string values, so editors and other tools can validate env
+configuration. Types without a standard format become patterns, and an
+x-envsync keyword keeps what JSON Schema cannot express.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		outputFile, _ := cmd.Flags().GetString("output")
+		return runSchemaExport(newFormatter(cmd), format, outputFile)
+	},
+}
+
+var schemaImportCmd = &cobra.Command{
+	Use:   "import [schema.json]",
+	Short: "Convert a JSON Schema into config variables",
+	Long: `Convert the properties of a JSON Schema object into schema variables.
+
+The conversion is lossy: keywords without an envsync equivalent are dropped
+with a warning. The schema is printed as a config block; with --merge the
+variables the config file does not define yet are added to it instead.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		merge, _ := cmd.Flags().GetBool("merge")
+		return runSchemaImport(newFormatter(cmd), args[0], merge)
+	},
+}
+
+func init() {
+	schemaInferCmd.Flags().Bool("merge", false, "merge into the config file instead of printing")
+
+	schemaExportCmd.Flags().String("format", "jsonschema", "output format, only jsonschema is supported")
+	schemaExportCmd.Flags().StringP("output", "o", "", "file to write instead of stdout")
+
+	schemaImportCmd.Flags().Bool("merge", false, "merge into the config file instead of printing")
+
+	schemaCmd.AddCommand(schemaInferCmd)
+	schemaCmd.AddCommand(schemaExportCmd)
+	schemaCmd.AddCommand(schemaImportCmd)
+	rootCmd.AddCommand(schemaCmd)
+}
+
+func runSchemaInfer(ctx context.Context, f *output.Formatter, locations []string, merge bool) error {
+	cfg, err := config.Load()
+
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	files := make([]env.Vars, 0, len(locations))
+
+	for _, location := range locations {
+		vars, err := readVars(ctx, cfg, location)
+
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", location, err)
+		}
+
+		files = append(files, vars)
+	}
+
+	inferred := env.InferSchema(files, env.InferOptions{IsSecret: cfg.Secrets.Matches})
+
+	return writeSchema(f, inferred, merge)
+}
+
+func runSchemaExport(f *output.Formatter, format, outputFile string) error {
+	if format != "jsonschema" {
+		return fmt.Errorf("unknown schema format %q, expected jsonschema", format)
+	}
+
+	cfg, err := config.Load()
+
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	document, err := schema.ToJSONSchema(cfg.Schema, cfg.Rules.AllowExtra)
+
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON Schema: %w", err)
+	}
+
+	document = append(document, '\n')
+
+	if outputFile == "" || outputFile == "-" {
+		_, err := f.Stdout().Write(document)
+		return err
+	}
+
+	if err := os.WriteFile(outputFile, document, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", outputFile, err)
+	}
+
+	return nil
+}
+
+func runSchemaImport(f *output.Formatter, filename string, merge bool) error {
+	data, err := os.ReadFile(filename)
+
+	if err != nil {
+		return fmt.Errorf("failed to read JSON Schema: %w", err)
+	}
+
+	imported, warnings, err := schema.FromJSONSchema(data)
+
+	if err != nil {
+		return err
+	}
+
+	for _, warning := range warnings {
+		fmt.Fprintf(f.Stderr(), "Warning: %s\n", warning)
+	}
+
+	return writeSchema(f, imported, merge)
+}
+
+// writeSchema prints s as a config block or merges it into the config file.
+func writeSchema(f *output.Formatter, s schema.Schema, merge bool) error {
+	if !merge {
+		encoder := yaml.NewEncoder(f.Stdout())
+		encoder.SetIndent(2)
+
+		if err := encoder.Encode(struct {
+			Schema schema.Schema `yaml:"schema"`
+		}{s}); err != nil {
+			return fmt.Errorf("failed to encode schema: %w", err)
+		}
+
+		return encoder.Close()
+	}
+
+	configFile := viper.ConfigFileUsed()
+	if configFile == "" {
+		configFile = ".envsync.yaml"
+	}
+
+	content, err := os.ReadFile(configFile)
+
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	merged, changed, err := config.MergeSchema(content, s)
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(configFile, merged, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	if len(changed) == 0 {
+		fmt.Fprintf(f.Stdout(), "✓ %s already covers every variable\n", configFile)
+		return nil
+	}
+
+	fmt.Fprintf(f.Stdout(), "Updated %d variables in %s: %s\n", len(changed), configFile, strings.Join(changed, ", "))
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tommyalmeida/envsync/internal/config"
+	"github.com/tommyalmeida/envsync/internal/env"
+)
+
+// ErrNotFound is returned by adapters whose stores tell a missing path apart
+// from an empty one when path does not exist.
+var ErrNotFound = errors.New("not found")
+
+// Adapter reads and writes environment variables stored under a path prefix
+// in a remote store such as a parameter store or secrets manager.
+type Adapter interface {
+	// Read returns every variable stored directly under path.
+	Read(ctx context.Context, path string) (env.Vars, error)
+	// Write creates or updates the given variables under path.
+	Write(ctx context.Context, path string, vars env.Vars) error
+	// Delete removes the given keys from path.
+	Delete(ctx context.Context, path string, keys []string) error
+	// List returns the sorted keys stored directly under path.
+	List(ctx context.Context, path string) ([]string, error)
+}
+
+// Factory builds an adapter from the `config` map of the adapter block.
+type Factory func(options map[string]string) (Adapter, error)
+
+type registration struct {
+	name    string
+	factory Factory
+}
+
+var registry = make(map[string]registration)
+
+// Register makes an adapter available under name and any aliases. Names are
+// also the URI schemes accepted by Open, so `ssm://app/prod` resolves to the
+// adapter registered as "ssm".
+func Register(name string, factory Factory, aliases ...string) {
+	reg := registration{name: name, factory: factory}
+
+	for _, key := range append([]string{name}, aliases...) {
+		if _, exists := registry[key]; exists {
+			panic(fmt.Sprintf("adapter %q registered twice", key))
+		}
+
+		registry[key] = reg
+	}
+}
+
+// Names returns every registered adapter name and alias.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+
+	for name := range registry {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func New(name string, options map[string]string) (Adapter, error) {
+	reg, exists := registry[name]
+
+	if !exists {
+		return nil, fmt.Errorf("unknown adapter %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+
+	return reg.factory(options)
+}
+
+// IsURI reports whether location names a registered adapter, such as
+// `ssm://app/prod`, rather than a local file.
+func IsURI(location string) bool {
+	scheme, _, ok := strings.Cut(location, "://")
+
+	if !ok {
+		return false
+	}
+
+	_, exists := registry[scheme]
+	return exists
+}
+
+// Open resolves an adapter URI to an adapter and the path it refers to. The
+// options of the configured adapter are used when it names the same adapter
+// as the URI scheme.
+func Open(uri string, cfg config.Adapter) (Adapter, string, error) {
+	scheme, path, ok := strings.Cut(uri, "://")
+
+	if !ok {
+		return nil, "", fmt.Errorf("invalid adapter URI %q", uri)
+	}
+
+	reg, exists := registry[scheme]
+
+	if !exists {
+		return nil, "", fmt.Errorf("unknown adapter %q in %s", scheme, uri)
+	}
+
+	var options map[string]string
+	if configured, exists := registry[cfg.Name]; exists && configured.name == reg.name {
+		options = cfg.Config
+	}
+
+	a, err := reg.factory(options)
+
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create %s adapter: %w", reg.name, err)
+	}
+
+	return a, path, nil
+}
+
+// Destination adapts a path in an adapter to an env.Destination so that a
+// sync can write to it.
+func Destination(ctx context.Context, a Adapter, uri, path string) env.Destination {
+	return &destination{ctx: ctx, adapter: a, uri: uri, path: path}
+}
+
+type destination struct {
+	ctx     context.Context
+	adapter Adapter
+	uri     string
+	path    string
+}
+
+func (d *destination) Location() string {
+	return d.uri
+}
+
+func (d *destination) Write(vars env.Vars, keys []string) error {
+	changed := make(env.Vars, len(keys))
+
+	for _, key := range keys {
+		changed[key] = vars[key]
+	}
+
+	return d.adapter.Write(d.ctx, d.path, changed)
+}
+
+func (d *destination) Remove(keys []string) error {
+	return d.adapter.Delete(d.ctx, d.path, keys)
+}
@@ -0,0 +1,96 @@
+package adapter_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tommyalmeida/envsync/internal/adapter"
+	"github.com/tommyalmeida/envsync/internal/config"
+	"github.com/tommyalmeida/envsync/internal/env"
+)
+
+type memoryAdapter struct {
+	options map[string]string
+	paths   map[string]env.Vars
+}
+
+func (m *memoryAdapter) Read(_ context.Context, path string) (env.Vars, error) {
+	return m.paths[path], nil
+}
+
+func (m *memoryAdapter) Write(_ context.Context, path string, vars env.Vars) error {
+	if m.paths[path] == nil {
+		m.paths[path] = make(env.Vars)
+	}
+
+	for k, v := range vars {
+		m.paths[path][k] = v
+	}
+
+	return nil
+}
+
+func (m *memoryAdapter) Delete(_ context.Context, path string, keys []string) error {
+	for _, key := range keys {
+		delete(m.paths[path], key)
+	}
+
+	return nil
+}
+
+func (m *memoryAdapter) List(_ context.Context, path string) ([]string, error) {
+	return m.paths[path].Keys(), nil
+}
+
+func init() {
+	adapter.Register("memory", func(options map[string]string) (adapter.Adapter, error) {
+		return &memoryAdapter{options: options, paths: make(map[string]env.Vars)}, nil
+	}, "mem")
+}
+
+func TestIsURI(t *testing.T) {
+	require.True(t, adapter.IsURI("ssm://app/prod"))
+	require.True(t, adapter.IsURI("memory://app"))
+	require.False(t, adapter.IsURI(".env"))
+	require.False(t, adapter.IsURI("unknown://app"))
+}
+
+func TestNew_UnknownAdapter(t *testing.T) {
+	_, err := adapter.New("unknown", nil)
+	require.ErrorContains(t, err, "unknown adapter")
+}
+
+func TestOpen(t *testing.T) {
+	cfg := config.Adapter{Name: "mem", Config: map[string]string{"region": "eu-west-1"}}
+
+	a, path, err := adapter.Open("memory://app/prod", cfg)
+	require.NoError(t, err)
+	require.Equal(t, "app/prod", path)
+	require.Equal(t, "eu-west-1", a.(*memoryAdapter).options["region"])
+
+	a, _, err = adapter.Open("memory://app/prod", config.Adapter{Name: "ssm", Config: map[string]string{"region": "x"}})
+	require.NoError(t, err)
+	require.Nil(t, a.(*memoryAdapter).options)
+
+	_, _, err = adapter.Open("nope://app", cfg)
+	require.Error(t, err)
+}
+
+func TestDestination_Sync(t *testing.T) {
+	a, path, err := adapter.Open("memory://app", config.Adapter{})
+	require.NoError(t, err)
+
+	cfg := &config.Config{}
+	dest := adapter.Destination(context.Background(), a, "memory://app", path)
+
+	result, err := env.NewSyncer(cfg).SyncTo(env.Vars{"A": "1", "B": "2"}, env.Vars{"A": "1"}, dest, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"B"}, result.Added)
+	require.Equal(t, "memory://app", result.FilePath)
+
+	vars, err := a.Read(context.Background(), "app")
+	require.NoError(t, err)
+	require.Equal(t, env.Vars{"B": "2"}, vars)
+}
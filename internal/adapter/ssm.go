@@ -0,0 +1,178 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+
+	"github.com/tommyalmeida/envsync/internal/env"
+)
+
+// ssmDeleteBatchSize is the maximum number of names DeleteParameters accepts.
+const ssmDeleteBatchSize = 10
+
+func init() {
+	Register("ssm", NewSSM, "aws")
+}
+
+// SSM stores each variable as a parameter named <path>/<KEY> in AWS Systems
+// Manager Parameter Store.
+//
+// Supported options:
+//
+//	region       AWS region, defaults to the SDK's resolution chain
+//	profile      shared config profile
+//	endpoint     custom endpoint URL, e.g. a local SSM stand-in
+//	secure       write SecureString parameters, defaults to "true"
+//	kms_key_id   KMS key used for SecureString parameters
+type SSM struct {
+	client   *ssm.Client
+	secure   bool
+	kmsKeyID string
+}
+
+func NewSSM(options map[string]string) (Adapter, error) {
+	ctx := context.Background()
+
+	var loadOptions []func(*awsconfig.LoadOptions) error
+
+	if region := options["region"]; region != "" {
+		loadOptions = append(loadOptions, awsconfig.WithRegion(region))
+	}
+
+	if profile := options["profile"]; profile != "" {
+		loadOptions = append(loadOptions, awsconfig.WithSharedConfigProfile(profile))
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, loadOptions...)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	secure := true
+	if value, exists := options["secure"]; exists {
+		secure, err = strconv.ParseBool(value)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid secure option %q: %w", value, err)
+		}
+	}
+
+	client := ssm.NewFromConfig(awsCfg, func(o *ssm.Options) {
+		if endpoint := options["endpoint"]; endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
+
+	return &SSM{
+		client:   client,
+		secure:   secure,
+		kmsKeyID: options["kms_key_id"],
+	}, nil
+}
+
+func (s *SSM) Read(ctx context.Context, path string) (env.Vars, error) {
+	prefix := ssmPrefix(path)
+	vars := make(env.Vars)
+
+	paginator := ssm.NewGetParametersByPathPaginator(s.client, &ssm.GetParametersByPathInput{
+		Path:           aws.String(ssmPath(path)),
+		WithDecryption: aws.Bool(true),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read parameters under %s: %w", prefix, err)
+		}
+
+		for _, param := range page.Parameters {
+			key := strings.TrimPrefix(aws.ToString(param.Name), prefix)
+			vars[key] = aws.ToString(param.Value)
+		}
+	}
+
+	return vars, nil
+}
+
+func (s *SSM) Write(ctx context.Context, path string, vars env.Vars) error {
+	prefix := ssmPrefix(path)
+
+	paramType := types.ParameterTypeString
+	if s.secure {
+		paramType = types.ParameterTypeSecureString
+	}
+
+	for _, key := range vars.Keys() {
+		input := &ssm.PutParameterInput{
+			Name:      aws.String(prefix + key),
+			Value:     aws.String(vars[key]),
+			Type:      paramType,
+			Overwrite: aws.Bool(true),
+		}
+
+		if s.secure && s.kmsKeyID != "" {
+			input.KeyId = aws.String(s.kmsKeyID)
+		}
+
+		if _, err := s.client.PutParameter(ctx, input); err != nil {
+			return fmt.Errorf("failed to write parameter %s: %w", prefix+key, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *SSM) Delete(ctx context.Context, path string, keys []string) error {
+	prefix := ssmPrefix(path)
+
+	for start := 0; start < len(keys); start += ssmDeleteBatchSize {
+		end := min(start+ssmDeleteBatchSize, len(keys))
+		names := make([]string, 0, end-start)
+
+		for _, key := range keys[start:end] {
+			names = append(names, prefix+key)
+		}
+
+		output, err := s.client.DeleteParameters(ctx, &ssm.DeleteParametersInput{Names: names})
+
+		if err != nil {
+			return fmt.Errorf("failed to delete parameters under %s: %w", prefix, err)
+		}
+
+		if len(output.InvalidParameters) > 0 {
+			return fmt.Errorf("parameters not found: %s", strings.Join(output.InvalidParameters, ", "))
+		}
+	}
+
+	return nil
+}
+
+func (s *SSM) List(ctx context.Context, path string) ([]string, error) {
+	vars, err := s.Read(ctx, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return vars.Keys(), nil
+}
+
+// ssmPath turns a URI path such as `app/prod` into the parameter hierarchy
+// `/app/prod`.
+func ssmPath(path string) string {
+	return "/" + strings.Trim(path, "/")
+}
+
+// ssmPrefix is the prefix of every parameter name under path.
+func ssmPrefix(path string) string {
+	return strings.TrimSuffix(ssmPath(path), "/") + "/"
+}
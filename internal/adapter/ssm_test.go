@@ -0,0 +1,196 @@
+package adapter_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tommyalmeida/envsync/internal/adapter"
+	"github.com/tommyalmeida/envsync/internal/env"
+)
+
+type fakeParameter struct {
+	Name  string
+	Type  string
+	Value string
+	KeyID string
+}
+
+// fakeSSM implements the subset of the SSM JSON protocol used by the adapter.
+// It returns two parameters per page to exercise pagination.
+type fakeSSM struct {
+	mu     sync.Mutex
+	params map[string]fakeParameter
+}
+
+func newFakeSSM(t *testing.T) (*fakeSSM, *httptest.Server) {
+	t.Helper()
+
+	fake := &fakeSSM{params: make(map[string]fakeParameter)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+
+	return fake, server
+}
+
+func (f *fakeSSM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var input map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var output any
+
+	switch strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "AmazonSSM.") {
+	case "GetParametersByPath":
+		output = f.getParametersByPath(input)
+	case "PutParameter":
+		param := fakeParameter{
+			Name:  input["Name"].(string),
+			Type:  input["Type"].(string),
+			Value: input["Value"].(string),
+		}
+		if keyID, ok := input["KeyId"].(string); ok {
+			param.KeyID = keyID
+		}
+		f.params[param.Name] = param
+		output = map[string]any{"Version": 1}
+	case "DeleteParameters":
+		var deleted, invalid []string
+		for _, name := range input["Names"].([]any) {
+			if _, ok := f.params[name.(string)]; ok {
+				delete(f.params, name.(string))
+				deleted = append(deleted, name.(string))
+			} else {
+				invalid = append(invalid, name.(string))
+			}
+		}
+		output = map[string]any{"DeletedParameters": deleted, "InvalidParameters": invalid}
+	default:
+		http.Error(w, "unsupported operation", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	_ = json.NewEncoder(w).Encode(output)
+}
+
+func (f *fakeSSM) getParametersByPath(input map[string]any) map[string]any {
+	prefix := strings.TrimSuffix(input["Path"].(string), "/") + "/"
+
+	var names []string
+	for name := range f.params {
+		if strings.HasPrefix(name, prefix) && !strings.Contains(strings.TrimPrefix(name, prefix), "/") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	start := 0
+	if token, ok := input["NextToken"].(string); ok {
+		start, _ = strconv.Atoi(token)
+	}
+	end := min(start+2, len(names))
+
+	params := make([]map[string]any, 0, end-start)
+	for _, name := range names[start:end] {
+		params = append(params, map[string]any{
+			"Name":  name,
+			"Type":  f.params[name].Type,
+			"Value": f.params[name].Value,
+		})
+	}
+
+	output := map[string]any{"Parameters": params}
+	if end < len(names) {
+		output["NextToken"] = strconv.Itoa(end)
+	}
+
+	return output
+}
+
+func newSSM(t *testing.T, server *httptest.Server, options map[string]string) adapter.Adapter {
+	t.Helper()
+
+	opts := map[string]string{"region": "us-east-1", "endpoint": server.URL}
+	for k, v := range options {
+		opts[k] = v
+	}
+
+	a, err := adapter.New("ssm", opts)
+	require.NoError(t, err)
+
+	return a
+}
+
+func TestSSM_WriteAndRead(t *testing.T) {
+	fake, server := newFakeSSM(t)
+	a := newSSM(t, server, map[string]string{"kms_key_id": "alias/app"})
+	ctx := context.Background()
+
+	vars := env.Vars{"A": "1", "B": "2", "C": "3", "D": "4", "E": "5"}
+	require.NoError(t, a.Write(ctx, "app/prod", vars))
+
+	fake.params["/app/prod/nested/IGNORED"] = fakeParameter{Name: "/app/prod/nested/IGNORED", Type: "String"}
+	fake.params["/app/staging/A"] = fakeParameter{Name: "/app/staging/A", Type: "String"}
+
+	require.Equal(t, "SecureString", fake.params["/app/prod/A"].Type)
+	require.Equal(t, "alias/app", fake.params["/app/prod/A"].KeyID)
+
+	read, err := a.Read(ctx, "/app/prod/")
+	require.NoError(t, err)
+	require.Equal(t, vars, read)
+
+	keys, err := a.List(ctx, "app/prod")
+	require.NoError(t, err)
+	require.Equal(t, []string{"A", "B", "C", "D", "E"}, keys)
+}
+
+func TestSSM_PlainStrings(t *testing.T) {
+	fake, server := newFakeSSM(t)
+	a := newSSM(t, server, map[string]string{"secure": "false"})
+
+	require.NoError(t, a.Write(context.Background(), "app", env.Vars{"PORT": "3000"}))
+	require.Equal(t, "String", fake.params["/app/PORT"].Type)
+}
+
+func TestSSM_Delete(t *testing.T) {
+	fake, server := newFakeSSM(t)
+	a := newSSM(t, server, nil)
+	ctx := context.Background()
+
+	vars := make(env.Vars)
+	for i := range 12 {
+		vars["KEY_"+strconv.Itoa(i)] = "value"
+	}
+	require.NoError(t, a.Write(ctx, "app", vars))
+
+	require.NoError(t, a.Delete(ctx, "app", vars.Keys()))
+	require.Empty(t, fake.params)
+
+	require.Error(t, a.Delete(ctx, "app", []string{"MISSING"}))
+}
+
+func TestSSM_InvalidOptions(t *testing.T) {
+	_, server := newFakeSSM(t)
+
+	_, err := adapter.New("ssm", map[string]string{"endpoint": server.URL, "secure": "maybe"})
+	require.Error(t, err)
+}
@@ -0,0 +1,391 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tommyalmeida/envsync/internal/env"
+)
+
+func init() {
+	Register("vault", NewVault)
+}
+
+// Vault stores all variables of a path as the fields of a single HashiCorp
+// Vault KV v2 secret. The first segment of the path is the secrets engine
+// mount, so `vault://secret/app/prod` is the secret `app/prod` in the
+// `secret` mount. A specific version can be read with `?version=N`.
+//
+// Supported options:
+//
+//	address        Vault address, defaults to $VAULT_ADDR
+//	token          token auth, defaults to $VAULT_TOKEN
+//	role_id        AppRole role ID, used instead of a token
+//	secret_id      AppRole secret ID
+//	approle_path   AppRole auth mount, defaults to "approle"
+//	namespace      Vault Enterprise namespace, defaults to $VAULT_NAMESPACE
+//	cas            use check-and-set on writes, defaults to "false"
+type Vault struct {
+	address     string
+	namespace   string
+	roleID      string
+	secretID    string
+	approlePath string
+	cas         bool
+	client      *http.Client
+
+	mu    sync.Mutex
+	token string
+}
+
+func NewVault(options map[string]string) (Adapter, error) {
+	v := &Vault{
+		address:     optionOrEnv(options, "address", "VAULT_ADDR"),
+		namespace:   optionOrEnv(options, "namespace", "VAULT_NAMESPACE"),
+		token:       optionOrEnv(options, "token", "VAULT_TOKEN"),
+		roleID:      options["role_id"],
+		secretID:    options["secret_id"],
+		approlePath: options["approle_path"],
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+
+	if v.address == "" {
+		return nil, fmt.Errorf("vault address is not set, use the address option or VAULT_ADDR")
+	}
+
+	if v.roleID != "" {
+		// AppRole takes precedence so a stray VAULT_TOKEN does not override
+		// the configured role.
+		v.token = ""
+	} else if v.token == "" {
+		return nil, fmt.Errorf("vault credentials are not set, use token, role_id/secret_id or VAULT_TOKEN")
+	}
+
+	if v.approlePath == "" {
+		v.approlePath = "approle"
+	}
+
+	if value, exists := options["cas"]; exists {
+		cas, err := strconv.ParseBool(value)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid cas option %q: %w", value, err)
+		}
+
+		v.cas = cas
+	}
+
+	v.address = strings.TrimSuffix(v.address, "/")
+
+	return v, nil
+}
+
+func optionOrEnv(options map[string]string, key, envKey string) string {
+	if value := options[key]; value != "" {
+		return value
+	}
+
+	return os.Getenv(envKey)
+}
+
+type vaultSecret struct {
+	Data    map[string]any
+	Version int
+}
+
+func (v *Vault) Read(ctx context.Context, path string) (env.Vars, error) {
+	secret, err := v.readSecret(ctx, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	vars := make(env.Vars, len(secret.Data))
+
+	for key, value := range secret.Data {
+		if s, ok := value.(string); ok {
+			vars[key] = s
+			continue
+		}
+
+		encoded, err := json.Marshal(value)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode field %s: %w", key, err)
+		}
+
+		vars[key] = string(encoded)
+	}
+
+	return vars, nil
+}
+
+// Write merges vars into the secret and stores the result as a new version.
+func (v *Vault) Write(ctx context.Context, path string, vars env.Vars) error {
+	// The first write creates the secret.
+	secret, err := v.readSecret(ctx, stripVersion(path))
+
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	data := secret.Data
+	if data == nil {
+		data = make(map[string]any, len(vars))
+	}
+
+	for key, value := range vars {
+		data[key] = value
+	}
+
+	return v.writeSecret(ctx, path, data, secret.Version)
+}
+
+// Delete removes keys from the secret and stores the result as a new
+// version, so earlier versions keep the deleted fields.
+func (v *Vault) Delete(ctx context.Context, path string, keys []string) error {
+	secret, err := v.readSecret(ctx, stripVersion(path))
+
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, key := range keys {
+		if _, exists := secret.Data[key]; !exists {
+			missing = append(missing, key)
+			continue
+		}
+
+		delete(secret.Data, key)
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("fields not found: %s", strings.Join(missing, ", "))
+	}
+
+	return v.writeSecret(ctx, path, secret.Data, secret.Version)
+}
+
+func (v *Vault) List(ctx context.Context, path string) ([]string, error) {
+	vars, err := v.Read(ctx, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return vars.Keys(), nil
+}
+
+func (v *Vault) readSecret(ctx context.Context, path string) (vaultSecret, error) {
+	mount, secretPath, version, err := splitVaultPath(path)
+
+	if err != nil {
+		return vaultSecret{}, err
+	}
+
+	endpoint := fmt.Sprintf("/v1/%s/data/%s", mount, secretPath)
+	if version != "" {
+		endpoint += "?version=" + url.QueryEscape(version)
+	}
+
+	var response struct {
+		Data struct {
+			Data     map[string]any `json:"data"`
+			Metadata struct {
+				Version int `json:"version"`
+			} `json:"metadata"`
+		} `json:"data"`
+	}
+
+	found, err := v.do(ctx, http.MethodGet, endpoint, nil, &response)
+
+	if err != nil {
+		return vaultSecret{}, fmt.Errorf("failed to read secret %s: %w", path, err)
+	}
+
+	if !found {
+		return vaultSecret{}, fmt.Errorf("failed to read secret %s: %w", path, ErrNotFound)
+	}
+
+	return vaultSecret{
+		Data:    response.Data.Data,
+		Version: response.Data.Metadata.Version,
+	}, nil
+}
+
+func (v *Vault) writeSecret(ctx context.Context, path string, data map[string]any, version int) error {
+	mount, secretPath, pinned, err := splitVaultPath(path)
+
+	if err != nil {
+		return err
+	}
+
+	if pinned != "" {
+		return fmt.Errorf("cannot write to a specific version of %s", path)
+	}
+
+	body := map[string]any{"data": data}
+
+	if v.cas {
+		body["options"] = map[string]any{"cas": version}
+	}
+
+	if _, err := v.do(ctx, http.MethodPost, fmt.Sprintf("/v1/%s/data/%s", mount, secretPath), body, nil); err != nil {
+		return fmt.Errorf("failed to write secret %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// do sends an authenticated request and decodes the response into out. It
+// reports false when Vault answers 404.
+func (v *Vault) do(ctx context.Context, method, endpoint string, body, out any) (bool, error) {
+	token, err := v.authenticate(ctx)
+
+	if err != nil {
+		return false, err
+	}
+
+	return v.request(ctx, method, endpoint, token, body, out)
+}
+
+func (v *Vault) request(ctx context.Context, method, endpoint, token string, body, out any) (bool, error) {
+	var reader io.Reader
+
+	if body != nil {
+		encoded, err := json.Marshal(body)
+
+		if err != nil {
+			return false, err
+		}
+
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, v.address+endpoint, reader)
+
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+
+	resp, err := v.client.Do(req)
+
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+
+	if resp.StatusCode >= 300 {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+
+		_ = json.NewDecoder(resp.Body).Decode(&vaultErr)
+
+		if len(vaultErr.Errors) > 0 {
+			return false, fmt.Errorf("vault returned %d: %s", resp.StatusCode, strings.Join(vaultErr.Errors, "; "))
+		}
+
+		return false, fmt.Errorf("vault returned %d", resp.StatusCode)
+	}
+
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return false, fmt.Errorf("failed to decode vault response: %w", err)
+		}
+	}
+
+	return true, nil
+}
+
+// authenticate returns the token to use, logging in with AppRole the first
+// time it is needed.
+func (v *Vault) authenticate(ctx context.Context) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.token != "" {
+		return v.token, nil
+	}
+
+	var response struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+
+	login := map[string]string{"role_id": v.roleID, "secret_id": v.secretID}
+	endpoint := fmt.Sprintf("/v1/auth/%s/login", strings.Trim(v.approlePath, "/"))
+
+	found, err := v.request(ctx, http.MethodPost, endpoint, "", login, &response)
+
+	if err != nil {
+		return "", fmt.Errorf("approle login failed: %w", err)
+	}
+
+	if !found || response.Auth.ClientToken == "" {
+		return "", fmt.Errorf("approle login failed: no token returned from %s", endpoint)
+	}
+
+	v.token = response.Auth.ClientToken
+
+	return v.token, nil
+}
+
+// splitVaultPath splits `secret/app/prod?version=3` into the mount, the
+// secret path and the requested version.
+func splitVaultPath(path string) (string, string, string, error) {
+	path, query, _ := strings.Cut(path, "?")
+
+	values, err := url.ParseQuery(query)
+
+	if err != nil {
+		return "", "", "", fmt.Errorf("invalid query in vault path %q: %w", path, err)
+	}
+
+	version := values.Get("version")
+	if version != "" {
+		if _, err := strconv.Atoi(version); err != nil {
+			return "", "", "", fmt.Errorf("invalid version %q in vault path %q", version, path)
+		}
+	}
+
+	mount, secretPath, ok := strings.Cut(strings.Trim(path, "/"), "/")
+
+	if !ok || mount == "" || secretPath == "" {
+		return "", "", "", fmt.Errorf("vault path %q must be <mount>/<secret path>", path)
+	}
+
+	return mount, secretPath, version, nil
+}
+
+func stripVersion(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	return path
+}
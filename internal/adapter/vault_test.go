@@ -0,0 +1,282 @@
+package adapter_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tommyalmeida/envsync/internal/adapter"
+	"github.com/tommyalmeida/envsync/internal/env"
+)
+
+// fakeVault implements the KV v2 data endpoints and AppRole login.
+type fakeVault struct {
+	mu        sync.Mutex
+	token     string
+	namespace string
+	versions  map[string][]map[string]any
+	logins    int
+}
+
+func newFakeVault(t *testing.T) (*fakeVault, *httptest.Server) {
+	t.Helper()
+
+	t.Setenv("VAULT_ADDR", "")
+	t.Setenv("VAULT_TOKEN", "")
+	t.Setenv("VAULT_NAMESPACE", "")
+
+	fake := &fakeVault{token: "root", versions: make(map[string][]map[string]any)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return fake, server
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path == "/v1/auth/approle/login" {
+		var login map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&login)
+
+		if login["role_id"] != "role" || login["secret_id"] != "secret" {
+			writeVaultError(w, http.StatusBadRequest, "invalid role or secret ID")
+			return
+		}
+
+		f.logins++
+		_ = json.NewEncoder(w).Encode(map[string]any{"auth": map[string]any{"client_token": f.token}})
+		return
+	}
+
+	if r.Header.Get("X-Vault-Token") != f.token {
+		writeVaultError(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	if r.Header.Get("X-Vault-Namespace") != f.namespace {
+		writeVaultError(w, http.StatusNotFound, "no handler for route")
+		return
+	}
+
+	mount, path, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v1/"), "/data/")
+	if !ok {
+		writeVaultError(w, http.StatusNotFound, "no handler for route")
+		return
+	}
+
+	key := mount + "/" + path
+	versions := f.versions[key]
+
+	switch r.Method {
+	case http.MethodGet:
+		version := len(versions)
+		if v := r.URL.Query().Get("version"); v != "" {
+			version, _ = strconv.Atoi(v)
+		}
+
+		if version == 0 || version > len(versions) {
+			writeVaultError(w, http.StatusNotFound, "")
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data": map[string]any{
+				"data":     versions[version-1],
+				"metadata": map[string]any{"version": version},
+			},
+		})
+	case http.MethodPost:
+		var body struct {
+			Data    map[string]any `json:"data"`
+			Options map[string]int `json:"options"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		if cas, exists := body.Options["cas"]; exists && cas != len(versions) {
+			writeVaultError(w, http.StatusBadRequest, "check-and-set parameter did not match the current version")
+			return
+		}
+
+		f.versions[key] = append(versions, body.Data)
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"version": len(f.versions[key])}})
+	}
+}
+
+func writeVaultError(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+
+	errors := []string{}
+	if message != "" {
+		errors = append(errors, message)
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]any{"errors": errors})
+}
+
+func TestVault_ReadWriteVersions(t *testing.T) {
+	fake, server := newFakeVault(t)
+	ctx := context.Background()
+
+	a, err := adapter.New("vault", map[string]string{"address": server.URL, "token": "root"})
+	require.NoError(t, err)
+
+	_, err = a.Read(ctx, "secret/app/prod")
+	require.ErrorIs(t, err, adapter.ErrNotFound)
+	require.ErrorIs(t, a.Delete(ctx, "secret/app/prod", []string{"A"}), adapter.ErrNotFound)
+
+	require.NoError(t, a.Write(ctx, "secret/app/prod", env.Vars{"A": "1", "B": "2"}))
+	require.NoError(t, a.Write(ctx, "secret/app/prod", env.Vars{"B": "3"}))
+	require.Len(t, fake.versions["secret/app/prod"], 2)
+
+	vars, err := a.Read(ctx, "secret/app/prod")
+	require.NoError(t, err)
+	require.Equal(t, env.Vars{"A": "1", "B": "3"}, vars)
+
+	vars, err = a.Read(ctx, "secret/app/prod?version=1")
+	require.NoError(t, err)
+	require.Equal(t, env.Vars{"A": "1", "B": "2"}, vars)
+
+	require.Error(t, a.Write(ctx, "secret/app/prod?version=1", env.Vars{"C": "4"}))
+
+	require.NoError(t, a.Delete(ctx, "secret/app/prod", []string{"A"}))
+	keys, err := a.List(ctx, "secret/app/prod")
+	require.NoError(t, err)
+	require.Equal(t, []string{"B"}, keys)
+
+	require.Error(t, a.Delete(ctx, "secret/app/prod", []string{"MISSING"}))
+}
+
+func TestVault_NonStringFields(t *testing.T) {
+	fake, server := newFakeVault(t)
+	fake.versions["secret/app"] = []map[string]any{{"PORT": float64(3000), "DEBUG": true}}
+
+	a, err := adapter.New("vault", map[string]string{"address": server.URL, "token": "root"})
+	require.NoError(t, err)
+
+	vars, err := a.Read(context.Background(), "secret/app")
+	require.NoError(t, err)
+	require.Equal(t, env.Vars{"PORT": "3000", "DEBUG": "true"}, vars)
+}
+
+func TestVault_CheckAndSet(t *testing.T) {
+	fake, server := newFakeVault(t)
+	ctx := context.Background()
+
+	a, err := adapter.New("vault", map[string]string{"address": server.URL, "token": "root", "cas": "true"})
+	require.NoError(t, err)
+
+	require.NoError(t, a.Write(ctx, "secret/app", env.Vars{"A": "1"}))
+	require.NoError(t, a.Write(ctx, "secret/app", env.Vars{"B": "2"}))
+	require.Len(t, fake.versions["secret/app"], 2)
+
+	_, err = adapter.New("vault", map[string]string{"address": server.URL, "token": "root", "cas": "sometimes"})
+	require.Error(t, err)
+}
+
+func TestVault_CheckAndSetConflict(t *testing.T) {
+	_, server := newFakeVault(t)
+	ctx := context.Background()
+
+	racer := &racingVault{server: server}
+	raceServer := httptest.NewServer(racer)
+	t.Cleanup(raceServer.Close)
+
+	a, err := adapter.New("vault", map[string]string{"address": raceServer.URL, "token": "root", "cas": "true"})
+	require.NoError(t, err)
+
+	require.NoError(t, a.Write(ctx, "secret/app", env.Vars{"A": "1"}))
+
+	racer.race = true
+	err = a.Write(ctx, "secret/app", env.Vars{"B": "2"})
+	require.ErrorContains(t, err, "check-and-set")
+}
+
+// racingVault proxies to the fake server and, when race is set, performs a
+// competing write right after each read.
+type racingVault struct {
+	server *httptest.Server
+	race   bool
+}
+
+func (r *racingVault) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	proxied, _ := http.NewRequest(req.Method, r.server.URL+req.URL.RequestURI(), req.Body)
+	proxied.Header = req.Header
+
+	resp, err := http.DefaultClient.Do(proxied)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	if r.race && req.Method == http.MethodGet {
+		competing, _ := http.NewRequest(http.MethodPost, r.server.URL+req.URL.Path, strings.NewReader(`{"data":{"A":"other"}}`))
+		competing.Header = req.Header
+		if resp, err := http.DefaultClient.Do(competing); err == nil {
+			resp.Body.Close()
+		}
+	}
+
+	w.WriteHeader(resp.StatusCode)
+	var body any
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func TestVault_AppRoleAndNamespace(t *testing.T) {
+	fake, server := newFakeVault(t)
+	fake.token = "approle-token"
+	fake.namespace = "team-a"
+	ctx := context.Background()
+
+	a, err := adapter.New("vault", map[string]string{
+		"address":   server.URL,
+		"role_id":   "role",
+		"secret_id": "secret",
+		"namespace": "team-a",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, a.Write(ctx, "kv/app", env.Vars{"A": "1"}))
+
+	vars, err := a.Read(ctx, "kv/app")
+	require.NoError(t, err)
+	require.Equal(t, env.Vars{"A": "1"}, vars)
+	require.Equal(t, 1, fake.logins)
+
+	bad, err := adapter.New("vault", map[string]string{"address": server.URL, "role_id": "role", "secret_id": "wrong"})
+	require.NoError(t, err)
+
+	_, err = bad.Read(ctx, "kv/app")
+	require.ErrorContains(t, err, "approle login failed")
+}
+
+func TestVault_Configuration(t *testing.T) {
+	_, server := newFakeVault(t)
+
+	_, err := adapter.New("vault", map[string]string{"token": "root"})
+	require.ErrorContains(t, err, "address")
+
+	_, err = adapter.New("vault", map[string]string{"address": server.URL})
+	require.ErrorContains(t, err, "credentials")
+
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "root")
+
+	a, err := adapter.New("vault", nil)
+	require.NoError(t, err)
+
+	_, err = a.Read(context.Background(), "secret")
+	require.ErrorContains(t, err, "<mount>/<secret path>")
+
+	require.True(t, adapter.IsURI("vault://secret/app/prod"))
+}
@@ -0,0 +1,456 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"reflect"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tommyalmeida/envsync/pkg/schema"
+)
+
+// Problem is a mistake in a config file.
+type Problem struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (p Problem) Error() string {
+	switch {
+	case p.Line == 0:
+		return fmt.Sprintf("%s: %s", p.File, p.Message)
+	case p.Column == 0:
+		return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+	default:
+		return fmt.Sprintf("%s:%d:%d: %s", p.File, p.Line, p.Column, p.Message)
+	}
+}
+
+// Problems is the error Load returns for a config it rejects. It lists every
+// problem found, in file order.
+type Problems []Problem
+
+func (p Problems) Error() string {
+	messages := make([]string, len(p))
+
+	for i, problem := range p {
+		messages[i] = problem.Error()
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// sorted returns the problems ordered by position, without duplicates.
+func (p Problems) sorted() Problems {
+	sorted := slices.Clone(p)
+
+	slices.SortStableFunc(sorted, func(a, b Problem) int {
+		return cmp.Or(
+			strings.Compare(a.File, b.File),
+			cmp.Compare(a.Line, b.Line),
+			cmp.Compare(a.Column, b.Column),
+		)
+	})
+
+	return slices.Compact(sorted)
+}
+
+var syntaxLine = regexp.MustCompile(`^yaml: line (\d+): `)
+
+func syntaxProblem(file string, err error) Problems {
+	problem := Problem{File: file, Message: err.Error()}
+
+	if match := syntaxLine.FindStringSubmatch(problem.Message); match != nil {
+		problem.Line, _ = strconv.Atoi(match[1])
+		problem.Message = strings.TrimPrefix(problem.Message, match[0])
+	}
+
+	return Problems{problem}
+}
+
+// index records the file of every node in the tree.
+func (l *loader) index(file string, node *yaml.Node) {
+	l.files[node] = file
+
+	for _, child := range node.Content {
+		l.index(file, child)
+	}
+}
+
+func (l *loader) problem(node *yaml.Node, format string, args ...any) Problem {
+	return Problem{
+		File:    l.files[node],
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func (l *loader) report(node *yaml.Node, format string, args ...any) {
+	l.problems = append(l.problems, l.problem(node, format, args...))
+}
+
+// checkNode reports keys that t has no field for and values that do not
+// decode into t, which yaml.v3 would otherwise drop or report without a file.
+func (l *loader) checkNode(node *yaml.Node, t reflect.Type, at string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// Environments have a short form that is only a path.
+	if t == reflect.TypeOf(Environment{}) && node.Kind == yaml.ScalarNode {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			l.report(node, "%s must be a mapping, not %s", describe(at), kindName(node))
+			return
+		}
+
+		fields := yamlFields(t)
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field, known := fields[key.Value]
+
+			if !known {
+				l.report(key, "unknown key %q%s%s", key.Value, in(at), suggestion(key.Value, fields))
+				continue
+			}
+
+			l.checkNode(value, field.Type, join(at, key.Value))
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			l.report(node, "%s must be a mapping, not %s", describe(at), kindName(node))
+			return
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			l.checkNode(node.Content[i+1], t.Elem(), join(at, node.Content[i].Value))
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			l.report(node, "%s must be a list, not %s", describe(at), kindName(node))
+			return
+		}
+
+		for i, item := range node.Content {
+			l.checkNode(item, t.Elem(), fmt.Sprintf("%s[%d]", at, i))
+		}
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!bool" {
+			l.report(node, "%s must be true or false, not %s", describe(at), kindName(node))
+		}
+	case reflect.Float64:
+		if node.Kind != yaml.ScalarNode || (node.ShortTag() != "!!int" && node.ShortTag() != "!!float") {
+			l.report(node, "%s must be a number, not %s", describe(at), kindName(node))
+		}
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			l.report(node, "%s must be a string, not %s", describe(at), kindName(node))
+		}
+	}
+}
+
+// yamlFields maps the yaml keys of a struct to its fields.
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		fields[name] = field
+	}
+
+	return fields
+}
+
+// suggestion proposes the known key closest to a misspelt one.
+func suggestion(key string, fields map[string]reflect.StructField) string {
+	best, distance := "", 3
+
+	for name := range fields {
+		if d := editDistance(key, name); d < distance || (d == distance && name < best) {
+			best, distance = name, d
+		}
+	}
+
+	if best == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(", did you mean %q?", best)
+}
+
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+func kindName(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+
+	switch node.ShortTag() {
+	case "!!bool":
+		return "the boolean " + node.Value
+	case "!!int", "!!float":
+		return "the number " + node.Value
+	case "!!null":
+		return "null"
+	default:
+		return strconv.Quote(node.Value)
+	}
+}
+
+func join(at, key string) string {
+	if at == "" {
+		return key
+	}
+
+	return at + "." + key
+}
+
+func describe(at string) string {
+	if at == "" {
+		return "the config"
+	}
+
+	return at
+}
+
+func in(at string) string {
+	if at == "" {
+		return ""
+	}
+
+	return " in " + at
+}
+
+// checkValues reports settings that decode but cannot work: unknown types,
+// invalid patterns, defaults that fail their own variable, mutually exclusive
+// groups that exclude nothing and rules that do not compile. root is the
+// merged document cfg was decoded from.
+func (l *loader) checkValues(root *yaml.Node, cfg *Config) {
+	for _, name := range slices.Sorted(maps.Keys(cfg.Schema.Variables)) {
+		l.checkVariable(cfg.Schema, name, lookupPath(root, "schema", "variables", name))
+	}
+
+	for i, group := range cfg.Schema.MutuallyExclusive {
+		if len(group) < 2 {
+			l.report(nodeAt(root, "schema", "mutually_exclusive", i), "mutually exclusive group needs at least two variables")
+		}
+	}
+
+	for i, rule := range cfg.Schema.Rules {
+		if err := cfg.Schema.CheckRule(rule); err != nil {
+			l.report(nodeAt(root, "schema", "rules", i), "invalid schema rule: %v", err)
+		}
+	}
+
+	for i, pattern := range cfg.Rules.IgnorePatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			l.report(nodeAt(root, "rules", "ignore_patterns", i), "invalid ignore pattern %q: %v", pattern, unwrapRegexp(err))
+		}
+	}
+
+	for i, pattern := range cfg.Secrets.Patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			l.report(nodeAt(root, "secrets", "patterns", i), "invalid secret pattern %q: %v", pattern, err)
+		}
+	}
+
+	l.checkLintRules(lookupPath(root, "lint", "rules"))
+
+	for _, environment := range cfg.EnvironmentNames() {
+		s, err := cfg.SchemaFor(environment)
+
+		if err != nil {
+			continue
+		}
+
+		overrides := lookupPath(root, "environments", environment, "variables")
+
+		for _, name := range slices.Sorted(maps.Keys(cfg.Environments[environment].Variables)) {
+			l.checkVariable(s, name, lookupPath(overrides, name), lookupPath(root, "schema", "variables", name))
+		}
+
+		// Overrides can change the types the rules are checked against.
+		for i, rule := range s.Rules {
+			if cfg.Schema.CheckRule(rule) != nil {
+				continue
+			}
+
+			if err := s.CheckRule(rule); err != nil {
+				l.report(nodeAt(root, "schema", "rules", i), "invalid schema rule for environment %q: %v", environment, err)
+			}
+		}
+	}
+}
+
+// checkLintRules checks the severities of lint rules. The rule names are
+// checked by the linter, which env files are parsed for.
+func (l *loader) checkLintRules(rules *yaml.Node) {
+	if rules == nil || rules.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 0; i+1 < len(rules.Content); i += 2 {
+		key, value := rules.Content[i], rules.Content[i+1]
+
+		if value.Kind == yaml.ScalarNode && !slices.Contains(LintSeverities, value.Value) {
+			l.report(value, "invalid severity %q for lint rule %s, expected one of %s",
+				value.Value, key.Value, strings.Join(LintSeverities, ", "))
+		}
+	}
+}
+
+// checkVariable checks the effective definition of a variable. nodes are the
+// definitions it was built from, the most specific first, and problems are
+// reported at the first one that sets the field.
+func (l *loader) checkVariable(s schema.Schema, name string, nodes ...*yaml.Node) {
+	variable := s.Variables[name]
+
+	at := func(key string) *yaml.Node {
+		for _, node := range nodes {
+			if value := lookupPath(node, key); value != nil {
+				return value
+			}
+		}
+
+		for _, node := range nodes {
+			if node != nil {
+				return node
+			}
+		}
+
+		return &yaml.Node{}
+	}
+
+	if variable.Type != "" && !slices.Contains(schema.Types, variable.Type) {
+		l.report(at("type"), "unknown type %q for %s, expected one of %s",
+			variable.Type, name, strings.Join(schema.Types, ", "))
+		return
+	}
+
+	if variable.Pattern != "" {
+		if _, err := regexp.Compile(variable.Pattern); err != nil {
+			l.report(at("pattern"), "invalid pattern for %s: %v", name, unwrapRegexp(err))
+			return
+		}
+	}
+
+	if variable.Default == "" {
+		return
+	}
+
+	for _, err := range s.ValidateVariable(name, variable.Default) {
+		l.report(at("default"), "default of %s is invalid: %s", name, err.Message)
+	}
+}
+
+// unwrapRegexp drops the "error parsing regexp: " prefix.
+func unwrapRegexp(err error) error {
+	var syntaxErr *syntax.Error
+
+	if errors.As(err, &syntaxErr) {
+		return fmt.Errorf("%s: `%s`", syntaxErr.Code, syntaxErr.Expr)
+	}
+
+	return err
+}
+
+// lookupPath follows keys through nested mappings.
+func lookupPath(node *yaml.Node, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		if node == nil || node.Kind != yaml.MappingNode {
+			return nil
+		}
+
+		node = lookupKey(node, key)
+	}
+
+	return node
+}
+
+// nodeAt returns item i of the list at keys, or the closest node that exists.
+func nodeAt(root *yaml.Node, keys ...any) *yaml.Node {
+	node := root
+
+	for _, key := range keys {
+		var next *yaml.Node
+
+		switch key := key.(type) {
+		case string:
+			next = lookupPath(node, key)
+		case int:
+			if node.Kind == yaml.SequenceNode && key < len(node.Content) {
+				next = node.Content[key]
+			}
+		}
+
+		if next == nil {
+			return node
+		}
+
+		node = next
+	}
+
+	return node
+}
@@ -0,0 +1,107 @@
+package config_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tommyalmeida/envsync/internal/config"
+)
+
+func TestLoad_Strict(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "unknown key",
+			content: "schema:\n  variables:\n    PORT:\n      requried: true\n",
+			want:    `a.yaml:4:7: unknown key "requried" in schema.variables.PORT, did you mean "required"?`,
+		},
+		{
+			name:    "unknown top-level key",
+			content: "schemas: {}\n",
+			want:    `a.yaml:1:1: unknown key "schemas", did you mean "schema"?`,
+		},
+		{
+			name:    "wrong kind",
+			content: "rules:\n  allow_extra: maybe\n",
+			want:    `a.yaml:2:16: rules.allow_extra must be true or false, not "maybe"`,
+		},
+		{
+			name:    "unknown type",
+			content: "schema:\n  variables:\n    LEVEL:\n      type: integr\n",
+			want:    `a.yaml:4:13: unknown type "integr" for LEVEL`,
+		},
+		{
+			name:    "invalid pattern",
+			content: "schema:\n  variables:\n    NAME:\n      pattern: \"([a-z\"\n",
+			want:    "a.yaml:4:16: invalid pattern for NAME: missing closing ]",
+		},
+		{
+			name:    "invalid default",
+			content: "schema:\n  variables:\n    PORT:\n      type: port\n      default: \"99999\"\n",
+			want:    "a.yaml:5:16: default of PORT is invalid",
+		},
+		{
+			name:    "mutually exclusive group of one",
+			content: "schema:\n  mutually_exclusive:\n    - [A, B]\n    - [C]\n",
+			want:    "a.yaml:4:7: mutually exclusive group needs at least two variables",
+		},
+		{
+			name:    "invalid ignore pattern",
+			content: "rules:\n  ignore_patterns:\n    - \"^CI_\"\n    - \"([\"\n",
+			want:    `a.yaml:4:7: invalid ignore pattern "(["`,
+		},
+		{
+			name:    "invalid environment default",
+			content: "schema:\n  variables:\n    PORT:\n      type: port\nenvironments:\n  prod:\n    variables:\n      PORT:\n        default: \"0\"\n",
+			want:    "a.yaml:9:18: default of PORT is invalid",
+		},
+		{
+			name:    "invalid lint severity",
+			content: "lint:\n  rules:\n    key-case: fatal\n",
+			want:    `a.yaml:3:15: invalid severity "fatal" for lint rule key-case, expected one of error, warn, off`,
+		},
+		{
+			name:    "syntax error",
+			content: "schema:\n  variables: [\n",
+			want:    "a.yaml:2: did not find expected node content",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfigFiles(t, map[string]string{"a.yaml": tt.content})
+
+			viper.SetConfigFile(filepath.Join(dir, "a.yaml"))
+			t.Cleanup(viper.Reset)
+
+			_, err := config.Load()
+			require.ErrorContains(t, err, filepath.Join(dir, tt.want))
+		})
+	}
+}
+
+func TestLoad_StrictReportsEveryProblem(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"base.yaml": "schema:\n  variables:\n    PORT:\n      type: prot\n",
+		"a.yaml":    "extends: base.yaml\nschema:\n  variables:\n    PORT:\n      default: \"80\"\n    NAME:\n      requried: true\n",
+	})
+
+	viper.SetConfigFile(filepath.Join(dir, "a.yaml"))
+	t.Cleanup(viper.Reset)
+
+	_, err := config.Load()
+
+	var problems config.Problems
+	require.True(t, errors.As(err, &problems))
+	require.Equal(t, config.Problems{
+		{File: filepath.Join(dir, "a.yaml"), Line: 7, Column: 7, Message: `unknown key "requried" in schema.variables.NAME, did you mean "required"?`},
+		{File: filepath.Join(dir, "base.yaml"), Line: 4, Column: 13, Message: `unknown type "prot" for PORT, expected one of string, number, integer, boolean, enum, duration, port, hostname, ip, cidr, uuid, json, base64, semver, url, email`},
+	}, problems)
+}
@@ -3,10 +3,10 @@ package config
 import (
 	"fmt"
 	"log/slog"
-	"os"
+	"path"
+	"slices"
 
 	"github.com/spf13/viper"
-	"gopkg.in/yaml.v3"
 
 	"github.com/tommyalmeida/envsync/pkg/schema"
 )
@@ -15,6 +15,29 @@ type Config struct {
 	Schema   schema.Schema     `yaml:"schema"`
 	Defaults map[string]string `yaml:"defaults"`
 	Rules    Rules             `yaml:"rules"`
+	Adapter  Adapter           `yaml:"adapter"`
+	Secrets  Secrets           `yaml:"secrets"`
+	Lint     Lint              `yaml:"lint"`
+
+	Environments map[string]Environment `yaml:"environments"`
+	// Workspaces are the directories or config files of sub-projects,
+	// relative to this config. See LoadWorkspaces.
+	Workspaces []string `yaml:"workspaces"`
+
+	// File is the path the config was loaded from. It is empty for the
+	// default config.
+	File string `yaml:"-"`
+	// Provenance lists, for every schema variable, the config files that
+	// define it in override order. The last one wins.
+	Provenance map[string][]string `yaml:"-"`
+	// VariableOrder lists the schema variables in the order the config
+	// declares them. Variables a config inherits come before its own.
+	VariableOrder []string `yaml:"-"`
+}
+
+type Adapter struct {
+	Name   string            `yaml:"name"`
+	Config map[string]string `yaml:"config"`
 }
 
 type Rules struct {
@@ -23,33 +46,176 @@ type Rules struct {
 	IgnorePatterns []string `yaml:"ignore_patterns"`
 }
 
+// Secrets configures which variables are secret and how their values are
+// masked in output.
+type Secrets struct {
+	// Patterns are name globs, such as *_TOKEN, whose variables are secret
+	// even without `secret: true` in the schema.
+	Patterns []string `yaml:"patterns"`
+	// Salt keeps masked hashes stable across runs. A random salt is used
+	// when it is empty.
+	Salt string `yaml:"salt"`
+}
+
+// Lint configures envsync lint.
+type Lint struct {
+	// Rules sets the severity of lint rules by name, one of LintSeverities.
+	Rules map[string]string `yaml:"rules"`
+}
+
+var LintSeverities = []string{"error", "warn", "off"}
+
+var DefaultSecretPatterns = []string{"*_KEY", "*_TOKEN", "*_PASSWORD"}
+
+// Matches reports whether name matches one of the secret patterns.
+func (s Secrets) Matches(name string) bool {
+	for _, pattern := range s.Patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsSecret reports whether the value of name must be masked in output.
+func (c *Config) IsSecret(name string) bool {
+	return c.Schema.Variables[name].Secret || c.Secrets.Matches(name)
+}
+
+// markSecrets marks the variables that match the secret patterns as secret,
+// so that validation messages never include their values.
+func (c *Config) markSecrets(variables map[string]schema.Variable) {
+	for name, variable := range variables {
+		if c.Secrets.Matches(name) {
+			variable.Secret = true
+			variables[name] = variable
+		}
+	}
+}
+
+// Load loads the config file viper found, or the default config when there
+// is none.
 func Load() (*Config, error) {
+	return LoadFile(viper.ConfigFileUsed())
+}
+
+// LoadFile loads the config at file, or the default config when file is
+// empty.
+func LoadFile(file string) (*Config, error) {
 	var cfg Config
 
 	cfg.Rules.AllowExtra = true
+	cfg.Secrets.Patterns = slices.Clone(DefaultSecretPatterns)
 	cfg.Defaults = make(map[string]string)
 
-	if viper.ConfigFileUsed() == "" {
+	if file == "" {
 		return &cfg, nil
 	}
 
-	configFile, err := os.ReadFile(viper.ConfigFileUsed())
+	l := newLoader()
+	node, err := l.load(file)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, err
 	}
 
-	err = yaml.Unmarshal(configFile, &cfg)
-
-	if err != nil {
+	// Values of the wrong kind are already problems; the rest of the config
+	// still decodes so that its values can be checked too.
+	if err := node.Decode(&cfg); err != nil && len(l.problems) == 0 {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	cfg.File = file
+	cfg.Provenance = l.provenance
+
+	if variables := lookupPath(node, "schema", "variables"); variables != nil {
+		for i := 0; i+1 < len(variables.Content); i += 2 {
+			cfg.VariableOrder = append(cfg.VariableOrder, variables.Content[i].Value)
+		}
+	}
+
 	if cfg.Schema.Variables == nil {
 		cfg.Schema.Variables = make(map[string]schema.Variable)
 	}
 
-	slog.Info("Loaded config data", "cfg", cfg)
+	// Mark pattern matches before checking defaults so that the problems
+	// reported never include their values either.
+	cfg.markSecrets(cfg.Schema.Variables)
+
+	l.checkValues(node, &cfg)
+
+	if len(l.problems) > 0 {
+		return nil, l.problems.sorted()
+	}
+
+	// checkValues has reported every rule that does not compile.
+	_ = cfg.Schema.CompileRules()
+
+	slog.Debug("Loaded config data", "cfg", cfg)
 
 	return &cfg, nil
 }
+
+// LogValue masks secret defaults and adapter credentials so that the config
+// can be logged.
+func (c Config) LogValue() slog.Value {
+	masked := c
+	masked.Defaults = make(map[string]string, len(c.Defaults))
+	masked.Schema.Variables = make(map[string]schema.Variable, len(c.Schema.Variables))
+	masked.Adapter.Config = make(map[string]string, len(c.Adapter.Config))
+
+	for name, value := range c.Defaults {
+		if c.IsSecret(name) {
+			value = maskedValue
+		}
+
+		masked.Defaults[name] = value
+	}
+
+	for name, variable := range c.Schema.Variables {
+		if c.IsSecret(name) && variable.Default != "" {
+			variable.Default = maskedValue
+		}
+
+		masked.Schema.Variables[name] = variable
+	}
+
+	for key, value := range c.Adapter.Config {
+		if slices.Contains(secretAdapterOptions, key) {
+			value = maskedValue
+		}
+
+		masked.Adapter.Config[key] = value
+	}
+
+	masked.Environments = make(map[string]Environment, len(c.Environments))
+
+	for name, environment := range c.Environments {
+		overrides := make(map[string]VariableOverride, len(environment.Variables))
+
+		for key, override := range environment.Variables {
+			if c.IsSecret(key) && override.Default != nil {
+				mask := maskedValue
+				override.Default = &mask
+			}
+
+			overrides[key] = override
+		}
+
+		environment.Variables = overrides
+		masked.Environments[name] = environment
+	}
+
+	if c.Secrets.Salt != "" {
+		masked.Secrets.Salt = maskedValue
+	}
+
+	// The named type drops this method so that the copy is logged as is.
+	type plain Config
+	return slog.AnyValue(plain(masked))
+}
+
+const maskedValue = "********"
+
+var secretAdapterOptions = []string{"token", "secret_id"}
@@ -1,8 +1,11 @@
 package config_test
 
 import (
+	"bytes"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/spf13/viper"
@@ -67,3 +70,163 @@ rules:
 	require.Len(t, cfg.Rules.IgnorePatterns, 1)
 	require.Equal(t, "IGNORED_*", cfg.Rules.IgnorePatterns[0])
 }
+
+func TestLoad_Secrets(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "config.yaml")
+
+	content := `schema:
+  variables:
+    DATABASE_URL:
+      secret: true
+      default: "postgres://admin:hunter2@db/app"
+    API_TOKEN:
+      default: "tok-123"
+    PORT:
+      default: "3000"
+defaults:
+  STRIPE_KEY: "sk_live_abc"
+  REGION: "eu-west-1"
+adapter:
+  name: vault
+  config:
+    address: "https://vault.internal"
+    token: "s.vaulttoken"
+`
+
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+	viper.SetConfigFile(filePath)
+	t.Cleanup(viper.Reset)
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+
+	require.Equal(t, config.DefaultSecretPatterns, cfg.Secrets.Patterns)
+	require.True(t, cfg.IsSecret("DATABASE_URL"))
+	require.True(t, cfg.IsSecret("API_TOKEN"))
+	require.True(t, cfg.IsSecret("STRIPE_KEY"))
+	require.True(t, cfg.IsSecret("DB_PASSWORD"))
+	require.False(t, cfg.IsSecret("PORT"))
+	require.True(t, cfg.Schema.Variables["API_TOKEN"].Secret, "pattern matches are marked in the schema")
+
+	var logs bytes.Buffer
+	slog.New(slog.NewTextHandler(&logs, nil)).Info("config", "cfg", cfg)
+
+	for _, secret := range []string{"hunter2", "tok-123", "sk_live_abc", "s.vaulttoken"} {
+		require.NotContains(t, logs.String(), secret)
+	}
+
+	require.Contains(t, logs.String(), "eu-west-1")
+	require.Contains(t, logs.String(), "vault.internal")
+}
+
+func TestLoad_SecretDefaultsInProblems(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "config.yaml")
+
+	content := `schema:
+  variables:
+    API_TOKEN:
+      type: enum
+      values: [a, b]
+      default: "tok-123"
+environments:
+  prod:
+    variables:
+      DB_PASSWORD:
+        pattern: "^[0-9]+$"
+        default: "hunter2"
+`
+
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+	viper.SetConfigFile(filePath)
+	t.Cleanup(viper.Reset)
+
+	_, err := config.Load()
+	require.ErrorContains(t, err, "default of API_TOKEN is invalid")
+	require.ErrorContains(t, err, "default of DB_PASSWORD is invalid")
+	require.NotContains(t, err.Error(), "tok-123")
+	require.NotContains(t, err.Error(), "hunter2")
+}
+
+func TestLoad_SecretPatterns(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "config.yaml")
+
+	require.NoError(t, os.WriteFile(filePath, []byte("secrets:\n  patterns: []\n"), 0644))
+	viper.SetConfigFile(filePath)
+	t.Cleanup(viper.Reset)
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	require.False(t, cfg.IsSecret("API_TOKEN"))
+
+	require.NoError(t, os.WriteFile(filePath, []byte("secrets:\n  patterns: [\"[\"]\n"), 0644))
+
+	_, err = config.Load()
+	require.ErrorContains(t, err, "invalid secret pattern")
+}
+
+func TestLoad_SchemaRules(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "config.yaml")
+
+	content := `schema:
+  variables:
+    WORKERS:
+      type: integer
+  rules:
+    - expr: WORKERS + 'a' == 'b'
+environments:
+  production:
+    variables:
+      WORKERS:
+        type: string
+`
+
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+	viper.SetConfigFile(filePath)
+	t.Cleanup(viper.Reset)
+
+	_, err := config.Load()
+	require.ErrorContains(t, err, "invalid schema rule")
+	require.ErrorContains(t, err, "mismatched types")
+
+	content = strings.Replace(content, "WORKERS + 'a' == 'b'", "WORKERS % 2 == 0", 1)
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+
+	_, err = config.Load()
+	require.ErrorContains(t, err, `invalid schema rule for environment "production"`)
+}
+
+func TestLoad_LintRules(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "config.yaml")
+
+	content := "lint:\n  rules:\n    key-case: off\n    duplicate-key: warn\n"
+
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+	viper.SetConfigFile(filePath)
+	t.Cleanup(viper.Reset)
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"key-case": "off", "duplicate-key": "warn"}, cfg.Lint.Rules)
+}
+
+func TestLoad_VariableOrder(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "config.yaml")
+
+	content := `schema:
+  variables:
+    PORT:
+      type: integer
+    HOST:
+      type: string
+    DEBUG:
+      type: boolean
+`
+
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+	viper.SetConfigFile(filePath)
+	t.Cleanup(viper.Reset)
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	require.Equal(t, []string{"PORT", "HOST", "DEBUG"}, cfg.VariableOrder)
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tommyalmeida/envsync/pkg/schema"
+)
+
+// Environment is a named profile from the environments section. It points at
+// an env file or adapter URI and can override parts of the schema.
+//
+// The short form `dev: .env.dev` sets only the path.
+type Environment struct {
+	Path      string                      `yaml:"path"`
+	Variables map[string]VariableOverride `yaml:"variables"`
+}
+
+// VariableOverride replaces the fields of a schema variable that are set. A
+// nil field keeps the value from the shared schema.
+type VariableOverride struct {
+	Required *bool   `yaml:"required"`
+	Type     *string `yaml:"type"`
+	Pattern  *string `yaml:"pattern"`
+	Default  *string `yaml:"default"`
+}
+
+func (e *Environment) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&e.Path)
+	}
+
+	type plain Environment
+	return node.Decode((*plain)(e))
+}
+
+// EnvironmentNames returns the configured environment names in sorted order.
+func (c *Config) EnvironmentNames() []string {
+	names := make([]string, 0, len(c.Environments))
+
+	for name := range c.Environments {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func (c *Config) Environment(name string) (Environment, error) {
+	environment, exists := c.Environments[name]
+
+	if !exists {
+		return Environment{}, fmt.Errorf("unknown environment %q", name)
+	}
+
+	return environment, nil
+}
+
+// SchemaFor returns the schema with the overrides of the named environment
+// applied. Overrides for variables missing from the schema add them.
+func (c *Config) SchemaFor(name string) (schema.Schema, error) {
+	environment, err := c.Environment(name)
+
+	if err != nil {
+		return schema.Schema{}, err
+	}
+
+	variables := make(map[string]schema.Variable, len(c.Schema.Variables))
+
+	for key, variable := range c.Schema.Variables {
+		variables[key] = variable
+	}
+
+	for key, override := range environment.Variables {
+		variables[key] = override.Apply(variables[key])
+	}
+
+	c.markSecrets(variables)
+
+	s := c.Schema
+	s.Variables = variables
+
+	// Overrides can change the types, and rules that do not compile against
+	// them are reported when the config loads.
+	_ = s.CompileRules()
+
+	return s, nil
+}
+
+func (o VariableOverride) Apply(variable schema.Variable) schema.Variable {
+	if o.Required != nil {
+		variable.Required = *o.Required
+	}
+
+	if o.Type != nil {
+		variable.Type = *o.Type
+	}
+
+	if o.Pattern != nil {
+		variable.Pattern = *o.Pattern
+	}
+
+	if o.Default != nil {
+		variable.Default = *o.Default
+	}
+
+	return variable
+}
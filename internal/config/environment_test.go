@@ -0,0 +1,77 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tommyalmeida/envsync/internal/config"
+)
+
+func TestLoad_Environments(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "config.yaml")
+
+	content := `schema:
+  variables:
+    PORT:
+      required: true
+      type: number
+      default: "3000"
+    DEBUG:
+      type: boolean
+environments:
+  dev: .env.dev
+  prod:
+    path: ssm://app/prod
+    variables:
+      PORT:
+        required: false
+        default: "80"
+      DEBUG:
+        pattern: "^false$"
+      SENTRY_DSN:
+        required: true
+        type: url
+`
+
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+	viper.SetConfigFile(filePath)
+	t.Cleanup(viper.Reset)
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"dev", "prod"}, cfg.EnvironmentNames())
+
+	dev, err := cfg.Environment("dev")
+	require.NoError(t, err)
+	require.Equal(t, ".env.dev", dev.Path)
+
+	devSchema, err := cfg.SchemaFor("dev")
+	require.NoError(t, err)
+	require.Equal(t, cfg.Schema.Variables, devSchema.Variables)
+
+	prodSchema, err := cfg.SchemaFor("prod")
+	require.NoError(t, err)
+
+	port := prodSchema.Variables["PORT"]
+	require.False(t, port.Required)
+	require.Equal(t, "80", port.Default)
+	require.Equal(t, "number", port.Type)
+
+	debug := prodSchema.Variables["DEBUG"]
+	require.Equal(t, "^false$", debug.Pattern)
+	require.Equal(t, "boolean", debug.Type)
+
+	sentry := prodSchema.Variables["SENTRY_DSN"]
+	require.True(t, sentry.Required)
+	require.Equal(t, "url", sentry.Type)
+
+	require.True(t, cfg.Schema.Variables["PORT"].Required, "overrides must not leak into the shared schema")
+
+	_, err = cfg.SchemaFor("staging")
+	require.Error(t, err)
+}
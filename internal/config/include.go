@@ -0,0 +1,211 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loader reads a config file together with the files it extends and
+// includes. Paths in extends and include are relative to the file that names
+// them.
+//
+// The override order is deterministic: first the extends base, then every
+// include in the order listed, then the file itself. Mappings are merged key
+// by key, so a file can change one field of a variable it inherits, while
+// scalars and lists replace what came before.
+type loader struct {
+	stack      []string
+	provenance map[string][]string
+	// files maps every node to the file it was read from, so that problems
+	// found after merging can still name the file.
+	files    map[*yaml.Node]string
+	problems Problems
+}
+
+func newLoader() *loader {
+	return &loader{
+		provenance: make(map[string][]string),
+		files:      make(map[*yaml.Node]string),
+	}
+}
+
+func (l *loader) load(file string) (*yaml.Node, error) {
+	absolute, err := filepath.Abs(file)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve config file %s: %w", file, err)
+	}
+
+	if slices.Contains(l.stack, absolute) {
+		var cycle []string
+		for _, f := range append(slices.Clone(l.stack), absolute) {
+			cycle = append(cycle, DisplayPath(f))
+		}
+
+		return nil, fmt.Errorf("config files include each other: %s", strings.Join(cycle, " -> "))
+	}
+
+	l.stack = append(l.stack, absolute)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	content, err := os.ReadFile(file)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var document yaml.Node
+
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, syntaxProblem(DisplayPath(absolute), err)
+	}
+
+	l.index(DisplayPath(absolute), &document)
+
+	root := &yaml.Node{Kind: yaml.MappingNode}
+
+	if len(document.Content) > 0 {
+		root = document.Content[0]
+	}
+
+	if root.Kind == yaml.ScalarNode && root.Tag == "!!null" {
+		root = &yaml.Node{Kind: yaml.MappingNode}
+	}
+
+	if root.Kind != yaml.MappingNode {
+		return nil, Problems{l.problem(root, "config must be a mapping, not %s", kindName(root))}
+	}
+
+	var extends string
+	var include []string
+
+	if node := takeKey(root, "extends"); node != nil {
+		if err := node.Decode(&extends); err != nil {
+			return nil, Problems{l.problem(node, "invalid extends, expected a file name, not %s", kindName(node))}
+		}
+	}
+
+	if node := takeKey(root, "include"); node != nil {
+		if err := node.Decode(&include); err != nil {
+			return nil, Problems{l.problem(node, "invalid include, expected a list of file names, not %s", kindName(node))}
+		}
+	}
+
+	l.checkNode(root, reflect.TypeOf(Config{}), "")
+
+	merged := &yaml.Node{Kind: yaml.MappingNode}
+	dir := filepath.Dir(file)
+
+	if extends != "" {
+		if merged, err = l.load(relativeTo(dir, extends)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range include {
+		fragment, err := l.load(relativeTo(dir, name))
+
+		if err != nil {
+			return nil, err
+		}
+
+		mergeNodes(merged, fragment)
+	}
+
+	l.record(DisplayPath(absolute), root)
+	mergeNodes(merged, root)
+
+	return merged, nil
+}
+
+// relativeTo resolves name, as written in a config in dir. Absolute names
+// are used as they are.
+func relativeTo(dir, name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+
+	return filepath.Join(dir, name)
+}
+
+// record notes that file defines the schema variables in root.
+func (l *loader) record(file string, root *yaml.Node) {
+	schema := lookupKey(root, "schema")
+	if schema == nil || schema.Kind != yaml.MappingNode {
+		return
+	}
+
+	variables := lookupKey(schema, "variables")
+	if variables == nil || variables.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 0; i+1 < len(variables.Content); i += 2 {
+		name := variables.Content[i].Value
+		l.provenance[name] = append(l.provenance[name], file)
+	}
+}
+
+// DisplayPath returns path relative to the working directory when it is
+// below it, for messages.
+func DisplayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+
+	if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+
+	return path
+}
+
+// mergeNodes merges the mapping src into the mapping dst.
+func mergeNodes(dst, src *yaml.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		index := keyIndex(dst, key.Value)
+
+		switch {
+		case index < 0:
+			dst.Content = append(dst.Content, key, value)
+		case dst.Content[index+1].Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			mergeNodes(dst.Content[index+1], value)
+		default:
+			// The node is replaced rather than overwritten so that it
+			// keeps pointing into the file it came from.
+			dst.Content[index+1] = value
+		}
+	}
+}
+
+// keyIndex returns the index of key in mapping.Content, or -1.
+func keyIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// takeKey removes key from mapping and returns its value.
+func takeKey(mapping *yaml.Node, key string) *yaml.Node {
+	i := keyIndex(mapping, key)
+	if i < 0 {
+		return nil
+	}
+
+	value := mapping.Content[i+1]
+	mapping.Content = slices.Delete(mapping.Content, i, i+2)
+
+	return value
+}
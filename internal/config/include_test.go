@@ -0,0 +1,149 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tommyalmeida/envsync/internal/config"
+)
+
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	return dir
+}
+
+func TestLoad_ExtendsAndInclude(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"shared/base.yaml": `schema:
+  variables:
+    LOG_LEVEL:
+      type: enum
+      values: [debug, info]
+      default: info
+rules:
+  allow_extra: false
+  ignore_patterns: ["CI_*"]
+`,
+		"shared/otel.yaml": `schema:
+  variables:
+    OTEL_ENDPOINT:
+      type: url
+    LOG_LEVEL:
+      default: debug
+      description: Log level
+`,
+		"service/.envsync.yaml": `extends: ../shared/base.yaml
+include:
+  - ../shared/otel.yaml
+schema:
+  variables:
+    LOG_LEVEL:
+      required: true
+    PORT:
+      type: port
+rules:
+  ignore_patterns: ["LOCAL_*"]
+`,
+	})
+
+	viper.SetConfigFile(filepath.Join(dir, "service/.envsync.yaml"))
+	t.Cleanup(viper.Reset)
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+
+	logLevel := cfg.Schema.Variables["LOG_LEVEL"]
+	require.Equal(t, "enum", logLevel.Type, "fields from the base are kept")
+	require.Equal(t, []string{"debug", "info"}, logLevel.Values)
+	require.Equal(t, "debug", logLevel.Default, "includes override the base")
+	require.Equal(t, "Log level", logLevel.Description)
+	require.True(t, logLevel.Required, "the file itself overrides its includes")
+
+	require.Contains(t, cfg.Schema.Variables, "OTEL_ENDPOINT")
+	require.Contains(t, cfg.Schema.Variables, "PORT")
+
+	require.False(t, cfg.Rules.AllowExtra)
+	require.Equal(t, []string{"LOCAL_*"}, cfg.Rules.IgnorePatterns, "lists are replaced, not appended")
+
+	require.Equal(t, []string{
+		filepath.Join(dir, "shared/base.yaml"),
+		filepath.Join(dir, "shared/otel.yaml"),
+		filepath.Join(dir, "service/.envsync.yaml"),
+	}, cfg.Provenance["LOG_LEVEL"])
+	require.Equal(t, []string{filepath.Join(dir, "shared/otel.yaml")}, cfg.Provenance["OTEL_ENDPOINT"])
+}
+
+func TestLoad_AbsolutePaths(t *testing.T) {
+	shared := writeConfigFiles(t, map[string]string{
+		"base.yaml": "schema:\n  variables:\n    PORT:\n      type: port\n",
+		"otel.yaml": "schema:\n  variables:\n    OTEL_ENDPOINT:\n      type: url\n",
+	})
+
+	content := "extends: " + filepath.Join(shared, "base.yaml") + "\ninclude:\n  - " + filepath.Join(shared, "otel.yaml") + "\n"
+	dir := writeConfigFiles(t, map[string]string{".envsync.yaml": content})
+
+	viper.SetConfigFile(filepath.Join(dir, ".envsync.yaml"))
+	t.Cleanup(viper.Reset)
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	require.Contains(t, cfg.Schema.Variables, "PORT")
+	require.Contains(t, cfg.Schema.Variables, "OTEL_ENDPOINT")
+}
+
+func TestLoad_IncludeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{
+			name: "cycle",
+			files: map[string]string{
+				"a.yaml": "include: [b.yaml]\n",
+				"b.yaml": "extends: a.yaml\n",
+			},
+			wantErr: "config files include each other",
+		},
+		{
+			name:    "self include",
+			files:   map[string]string{"a.yaml": "include: [a.yaml]\n"},
+			wantErr: "config files include each other",
+		},
+		{
+			name:    "missing file",
+			files:   map[string]string{"a.yaml": "extends: missing.yaml\n"},
+			wantErr: "failed to read config file",
+		},
+		{
+			name:    "invalid include",
+			files:   map[string]string{"a.yaml": "include: {a: b}\n"},
+			wantErr: "invalid include",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfigFiles(t, tt.files)
+
+			viper.SetConfigFile(filepath.Join(dir, "a.yaml"))
+			t.Cleanup(viper.Reset)
+
+			_, err := config.Load()
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
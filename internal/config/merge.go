@@ -0,0 +1,113 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tommyalmeida/envsync/pkg/schema"
+)
+
+// MergeSchema adds the variables of s that the schema section of the config
+// document in content does not define yet. Existing variables are left as
+// they are, so hand-edited fields, comments and ordering are kept. It returns
+// the new document and the names of the variables it added.
+func MergeSchema(content []byte, s schema.Schema) ([]byte, []string, error) {
+	var doc yaml.Node
+
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("config must be a mapping")
+	}
+
+	schemaNode, err := mappingValue(root, "schema")
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	variables, err := mappingValue(schemaNode, "variables")
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := make([]string, 0, len(s.Variables))
+	for name := range s.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var changed []string
+
+	for _, name := range names {
+		var inferred yaml.Node
+
+		if err := inferred.Encode(s.Variables[name]); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode %s: %w", name, err)
+		}
+
+		if lookupKey(variables, name) != nil {
+			continue
+		}
+
+		variables.Content = append(variables.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, &inferred)
+		changed = append(changed, name)
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	return buf.Bytes(), changed, nil
+}
+
+// mappingValue returns the mapping under key, adding an empty one when the
+// key is missing or null.
+func mappingValue(mapping *yaml.Node, key string) (*yaml.Node, error) {
+	value := lookupKey(mapping, key)
+
+	if value == nil {
+		value = &yaml.Node{Kind: yaml.MappingNode}
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+		return value, nil
+	}
+
+	if value.Kind == yaml.ScalarNode && value.Tag == "!!null" {
+		*value = yaml.Node{Kind: yaml.MappingNode}
+	}
+
+	if value.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s in config must be a mapping", key)
+	}
+
+	return value, nil
+}
+
+func lookupKey(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tommyalmeida/envsync/internal/config"
+	"github.com/tommyalmeida/envsync/pkg/schema"
+)
+
+func TestMergeSchema(t *testing.T) {
+	content := `# Service config
+schema:
+  variables:
+    PORT:
+      type: number # chosen by hand
+      description: "Server port"
+rules:
+  allow_extra: true
+`
+
+	inferred := schema.Schema{Variables: map[string]schema.Variable{
+		"PORT":    {Required: true, Type: "port"},
+		"API_KEY": {Secret: true},
+	}}
+
+	merged, changed, err := config.MergeSchema([]byte(content), inferred)
+	require.NoError(t, err)
+	require.Equal(t, []string{"API_KEY"}, changed)
+
+	expected := `# Service config
+schema:
+  variables:
+    PORT:
+      type: number # chosen by hand
+      description: "Server port"
+    API_KEY:
+      secret: true
+rules:
+  allow_extra: true
+`
+	require.Equal(t, expected, string(merged))
+
+	_, changed, err = config.MergeSchema(merged, inferred)
+	require.NoError(t, err)
+	require.Empty(t, changed)
+
+	merged, _, err = config.MergeSchema(nil, inferred)
+	require.NoError(t, err)
+	require.Equal(t, "schema:\n  variables:\n    API_KEY:\n      secret: true\n    PORT:\n      required: true\n      type: port\n", string(merged))
+
+	_, _, err = config.MergeSchema([]byte("schema: [1]\n"), inferred)
+	require.Error(t, err)
+}
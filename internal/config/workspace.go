@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileName is the name of the config file Find looks for.
+const FileName = ".envsync.yaml"
+
+// Find looks for the config file in dir and then in its parents. The search
+// stops at the root of the git repository dir is in, or at the root of the
+// file system when there is none. It returns "" when no config is found.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+
+	for {
+		candidate := filepath.Join(dir, FileName)
+
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return "", nil
+		}
+
+		parent := filepath.Dir(dir)
+
+		if parent == dir {
+			return "", nil
+		}
+
+		dir = parent
+	}
+}
+
+// ResolvePath returns where a path from the config points to. Relative file
+// paths are relative to the config file, so that envsync finds the same files
+// from any directory. Adapter URIs and absolute paths are returned unchanged.
+func (c *Config) ResolvePath(path string) string {
+	if c.File == "" || path == "" || filepath.IsAbs(path) || strings.Contains(path, "://") {
+		return path
+	}
+
+	return DisplayPath(filepath.Join(filepath.Dir(c.File), path))
+}
+
+// Workspace is a sub-project from the workspaces section.
+type Workspace struct {
+	// Name is the entry as written in the workspaces section.
+	Name   string
+	Config *Config
+}
+
+// LoadWorkspaces loads the config of every workspace, in the order listed.
+// A workspace can name a directory, which must hold a .envsync.yaml, or a
+// config file.
+func (c *Config) LoadWorkspaces() ([]Workspace, error) {
+	workspaces := make([]Workspace, 0, len(c.Workspaces))
+
+	for _, name := range c.Workspaces {
+		workspace, err := c.LoadWorkspace(name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		workspaces = append(workspaces, workspace)
+	}
+
+	return workspaces, nil
+}
+
+// LoadWorkspace loads the config of the workspace listed as name.
+func (c *Config) LoadWorkspace(name string) (Workspace, error) {
+	file := filepath.Join(filepath.Dir(c.File), name)
+
+	if info, err := os.Stat(file); err == nil && info.IsDir() {
+		file = filepath.Join(file, FileName)
+	}
+
+	cfg, err := LoadFile(file)
+
+	if err != nil {
+		return Workspace{}, fmt.Errorf("failed to load workspace %s: %w", name, err)
+	}
+
+	return Workspace{Name: filepath.Clean(name), Config: cfg}, nil
+}
@@ -0,0 +1,83 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tommyalmeida/envsync/internal/config"
+)
+
+func TestFind(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"repo/.envsync.yaml":              "",
+		"repo/.git/HEAD":                  "",
+		"repo/services/api/.envsync.yaml": "",
+		"repo/services/api/src/main.go":   "",
+		"repo/services/web/src/main.go":   "",
+		"repo/nested/.git":                "gitdir: ../.git/modules/nested\n",
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".envsync.yaml"), nil, 0644))
+
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{"repo", "repo/.envsync.yaml"},
+		{"repo/services/api/src", "repo/services/api/.envsync.yaml"},
+		{"repo/services/web/src", "repo/.envsync.yaml"},
+		{"repo/nested", ""},
+	}
+
+	for _, tt := range tests {
+		found, err := config.Find(filepath.Join(dir, tt.dir))
+		require.NoError(t, err)
+
+		if tt.want == "" {
+			require.Empty(t, found, "the search stops at the repository root")
+			continue
+		}
+
+		require.Equal(t, filepath.Join(dir, tt.want), found)
+	}
+}
+
+func TestLoadWorkspaces(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		".envsync.yaml": `workspaces:
+  - services/api
+  - services/web/envsync.yaml
+`,
+		"services/api/.envsync.yaml": `environments:
+  dev: .env.dev
+  prod: ssm://api/prod
+`,
+		"services/web/envsync.yaml": `schema:
+  variables:
+    API_URL:
+      type: url
+`,
+	})
+
+	cfg, err := config.LoadFile(filepath.Join(dir, ".envsync.yaml"))
+	require.NoError(t, err)
+
+	workspaces, err := cfg.LoadWorkspaces()
+	require.NoError(t, err)
+	require.Len(t, workspaces, 2)
+
+	api := workspaces[0]
+	require.Equal(t, "services/api", api.Name)
+	require.Equal(t, filepath.Join(dir, "services/api/.env.dev"), api.Config.ResolvePath(api.Config.Environments["dev"].Path))
+	require.Equal(t, "ssm://api/prod", api.Config.ResolvePath(api.Config.Environments["prod"].Path))
+
+	web := workspaces[1]
+	require.Equal(t, "services/web/envsync.yaml", web.Name)
+	require.Contains(t, web.Config.Schema.Variables, "API_URL")
+
+	cfg.Workspaces = []string{"missing"}
+	_, err = cfg.LoadWorkspaces()
+	require.ErrorContains(t, err, "failed to load workspace missing")
+}
@@ -1,10 +1,18 @@
 package env
 
+import (
+	"maps"
+	"slices"
+	"sort"
+)
+
 type DiffResult struct {
 	Missing   []string        `json:"missing"`
 	Extra     []string        `json:"extra"`
 	Different map[string]Diff `json:"different"`
 	Same      []string        `json:"same"`
+	Ignored   []string        `json:"ignored,omitempty"`
+	Rules     []RuleMatch     `json:"rules,omitempty"`
 }
 
 type Diff struct {
@@ -13,10 +21,35 @@ type Diff struct {
 }
 
 func CompareEnvs(source, target Vars) DiffResult {
+	return CompareEnvsWithRules(source, target, nil)
+}
+
+// CompareEnvsWithRules compares source and target after dropping every key
+// matched by the rule set's ignore patterns from both sides. When the rule set
+// has a schema, require_all reports schema variables that neither side sets
+// as missing, and allow_extra records the target variables outside the
+// schema.
+func CompareEnvsWithRules(source, target Vars, rules *RuleSet) DiffResult {
 	result := DiffResult{
 		Different: make(map[string]Diff, len(source)),
 	}
 
+	source, sourceIgnored := rules.Filter(source)
+	target, targetIgnored := rules.Filter(target)
+
+	seen := make(map[string]bool)
+	for _, match := range append(sourceIgnored, targetIgnored...) {
+		if seen[match.Variable] {
+			continue
+		}
+
+		seen[match.Variable] = true
+		result.Ignored = append(result.Ignored, match.Variable)
+		result.Rules = append(result.Rules, match)
+	}
+
+	sort.Strings(result.Ignored)
+
 	for key, sourceValue := range source {
 		if targetValue, exists := target[key]; exists {
 			if sourceValue != targetValue {
@@ -38,5 +71,43 @@ func CompareEnvs(source, target Vars) DiffResult {
 		}
 	}
 
+	if rules != nil && rules.schema != nil {
+		result.checkSchema(source, target, rules)
+	}
+
+	sort.Strings(result.Missing)
+	sort.Strings(result.Extra)
+	sort.Strings(result.Same)
+	sort.SliceStable(result.Rules, func(i, j int) bool {
+		return result.Rules[i].Variable < result.Rules[j].Variable
+	})
+
 	return result
 }
+
+// checkSchema applies the rules that need the schema to the filtered source
+// and target.
+func (r *DiffResult) checkSchema(source, target Vars, rules *RuleSet) {
+	if rules.RequireAll {
+		for _, key := range slices.Sorted(maps.Keys(rules.schema)) {
+			_, inSource := source[key]
+			_, inTarget := target[key]
+			_, ignored := rules.Ignored(key)
+
+			if inSource || inTarget || ignored {
+				continue
+			}
+
+			r.Missing = append(r.Missing, key)
+			r.Rules = append(r.Rules, RuleMatch{Variable: key, Rule: RuleRequireAll})
+		}
+	}
+
+	if !rules.AllowExtra {
+		for _, key := range target.Keys() {
+			if _, inSchema := rules.schema[key]; !inSchema {
+				r.Rules = append(r.Rules, RuleMatch{Variable: key, Rule: RuleAllowExtra})
+			}
+		}
+	}
+}
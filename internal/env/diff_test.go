@@ -4,7 +4,9 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/tommyalmeida/envsync/internal/config"
 	"github.com/tommyalmeida/envsync/internal/env"
+	"github.com/tommyalmeida/envsync/pkg/schema"
 )
 
 func TestCompareEnvs(t *testing.T) {
@@ -106,3 +108,79 @@ func TestCompareEnvs(t *testing.T) {
 		})
 	}
 }
+
+func TestCompareEnvsWithRules(t *testing.T) {
+	rules, err := env.NewRuleSet(config.Rules{IgnorePatterns: []string{"^DEBUG_", "^TEMP_"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	source := env.Vars{
+		"VAR1":       "value1",
+		"DEBUG_SQL":  "true",
+		"TEMP_TOKEN": "abc",
+	}
+	target := env.Vars{
+		"VAR1":       "value1",
+		"DEBUG_SQL":  "false",
+		"DEBUG_HTTP": "true",
+	}
+
+	result := env.CompareEnvsWithRules(source, target, rules)
+
+	if len(result.Missing) != 0 || len(result.Extra) != 0 || len(result.Different) != 0 {
+		t.Errorf("expected ignored keys to be dropped, got %+v", result)
+	}
+
+	expectedIgnored := []string{"DEBUG_HTTP", "DEBUG_SQL", "TEMP_TOKEN"}
+	if !reflect.DeepEqual(result.Ignored, expectedIgnored) {
+		t.Errorf("expected Ignored=%v, got Ignored=%v", expectedIgnored, result.Ignored)
+	}
+
+	for _, match := range result.Rules {
+		if match.Rule != env.RuleIgnorePatterns {
+			t.Errorf("expected rule %s for %s, got %s", env.RuleIgnorePatterns, match.Variable, match.Rule)
+		}
+	}
+}
+
+func TestCompareEnvsWithRules_Schema(t *testing.T) {
+	s := schema.Schema{
+		Variables: map[string]schema.Variable{
+			"HOST":    {},
+			"PORT":    {},
+			"TEMP_ID": {},
+		},
+	}
+
+	rules, err := env.NewRuleSet(config.Rules{RequireAll: true, IgnorePatterns: []string{"^TEMP_"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := env.CompareEnvsWithRules(env.Vars{"HOST": "a"}, env.Vars{"HOST": "a", "LEGACY": "1"}, rules.WithSchema(s))
+
+	if !reflect.DeepEqual(result.Missing, []string{"PORT"}) {
+		t.Errorf("expected PORT to be missing, got %v", result.Missing)
+	}
+
+	expected := []env.RuleMatch{
+		{Variable: "LEGACY", Rule: env.RuleAllowExtra},
+		{Variable: "PORT", Rule: env.RuleRequireAll},
+	}
+
+	if !reflect.DeepEqual(result.Rules, expected) {
+		t.Errorf("expected rules %v, got %v", expected, result.Rules)
+	}
+
+	rules, err = env.NewRuleSet(config.Rules{RequireAll: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result = env.CompareEnvsWithRules(env.Vars{}, env.Vars{"LEGACY": "1"}, rules)
+
+	if len(result.Missing) != 0 || len(result.Rules) != 0 {
+		t.Errorf("expected no schema rules without a schema, got %+v", result)
+	}
+}
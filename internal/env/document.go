@@ -0,0 +1,561 @@
+package env
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tommyalmeida/envsync/pkg/schema"
+)
+
+type LineKind int
+
+const (
+	LineBlank LineKind = iota
+	LineComment
+	LineAssignment
+	// LineInvalid holds text the parser could not make sense of. It is kept
+	// verbatim so that writing the document never loses content.
+	LineInvalid
+)
+
+// Line is a single logical line of an env file. Assignments with multi-line
+// quoted values span several physical lines but are kept as one Line.
+type Line struct {
+	Kind LineKind
+
+	// Raw is the original text of the line without its trailing newline. It is
+	// written back unchanged unless the line has been modified.
+	Raw string
+
+	// Number is the 1-based physical line the line starts on, and Column the
+	// 1-based byte column of the key of an assignment. Both are 0 for lines
+	// that were not parsed.
+	Number int
+	Column int
+
+	Key     string
+	Value   string
+	Export  bool
+	Quote   byte
+	Comment string
+	Indent  string
+
+	// template is the value in the syntax understood by Expand.
+	template string
+	modified bool
+}
+
+// Document is an env file parsed into lines so it can be edited and written
+// back with its comments, blank lines, ordering and quoting intact.
+type Document struct {
+	Lines []*Line
+
+	// File is the file the document was read from, if any.
+	File string
+
+	// TrailingNewline records whether the source ended with a newline.
+	TrailingNewline bool
+
+	// LineEnding is written between lines: "\r\n" when the first line of the
+	// source ended with one, "\n" otherwise. Lines are parsed with "\n" in
+	// either case.
+	LineEnding string
+}
+
+func NewDocument() *Document {
+	return &Document{TrailingNewline: true, LineEnding: "\n"}
+}
+
+func ParseDocumentFile(filename string) (*Document, error) {
+	file, err := os.Open(filename)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to open env file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	doc, err := ParseDocument(file)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse env file %s: %w", filename, err)
+	}
+
+	doc.File = filename
+
+	return doc, nil
+}
+
+func ParseDocument(r io.Reader) (*Document, error) {
+	content, err := io.ReadAll(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{
+		TrailingNewline: len(content) == 0 || bytes.HasSuffix(content, []byte("\n")),
+		LineEnding:      "\n",
+	}
+
+	if first, _, found := bytes.Cut(content, []byte("\n")); found && bytes.HasSuffix(first, []byte("\r")) {
+		doc.LineEnding = "\r\n"
+	}
+
+	if len(content) == 0 {
+		return doc, nil
+	}
+
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	physical := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+
+	for i := 0; i < len(physical); i++ {
+		line, consumed := parseLine(physical[i:])
+		line.Number = i + 1
+		doc.Lines = append(doc.Lines, line)
+		i += consumed - 1
+	}
+
+	return doc, nil
+}
+
+// parseLine parses the logical line starting at lines[0] and reports how many
+// physical lines it consumed.
+func parseLine(lines []string) (*Line, int) {
+	raw := lines[0]
+	trimmed := strings.TrimLeft(raw, " \t")
+	indent := raw[:len(raw)-len(trimmed)]
+
+	switch {
+	case strings.TrimSpace(trimmed) == "":
+		return &Line{Kind: LineBlank, Raw: raw}, 1
+	case strings.HasPrefix(trimmed, "#"):
+		return &Line{Kind: LineComment, Raw: raw, Comment: trimmed}, 1
+	}
+
+	line := &Line{Kind: LineAssignment, Indent: indent}
+	rest := trimmed
+
+	if after, ok := strings.CutPrefix(rest, "export "); ok {
+		line.Export = true
+		rest = strings.TrimLeft(after, " \t")
+	}
+
+	eq := strings.IndexByte(rest, '=')
+	if eq <= 0 {
+		return &Line{Kind: LineInvalid, Raw: raw}, 1
+	}
+
+	line.Column = len(raw) - len(rest) + 1
+
+	line.Key = strings.TrimSpace(rest[:eq])
+	if !isValidKey(line.Key) {
+		return &Line{Kind: LineInvalid, Raw: raw}, 1
+	}
+
+	rest = strings.TrimLeft(rest[eq+1:], " \t")
+
+	if rest != "" && isQuote(rest[0]) {
+		quote := rest[0]
+		body := rest[1:]
+		consumed := 1
+
+		for {
+			if end := closingQuote(body, quote); end >= 0 {
+				line.Quote = quote
+				line.Value, line.template = unquote(body[:end], quote)
+				line.Comment = inlineComment(body[end+1:])
+				line.Raw = strings.Join(lines[:consumed], "\n")
+
+				return line, consumed
+			}
+
+			if consumed >= len(lines) {
+				break
+			}
+
+			body += "\n" + lines[consumed]
+			consumed++
+		}
+
+		return &Line{Kind: LineInvalid, Raw: raw}, 1
+	}
+
+	value, comment := splitInlineComment(rest)
+	line.Value = value
+	line.template = strings.ReplaceAll(value, `\$`, "$$")
+	line.Comment = comment
+	line.Raw = raw
+
+	return line, 1
+}
+
+func isQuote(c byte) bool {
+	return c == '"' || c == '\''
+}
+
+func closingQuote(body string, quote byte) int {
+	for i := 0; i < len(body); i++ {
+		if quote == '"' && body[i] == '\\' {
+			i++
+			continue
+		}
+
+		if body[i] == quote {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// unquote returns the value of a quoted body and its template for Expand.
+// Single-quoted values are literal; double-quoted values support escapes and
+// references, where \$ is a literal dollar sign.
+func unquote(body string, quote byte) (string, string) {
+	if quote != '"' {
+		return body, EscapeTemplate(body)
+	}
+
+	var value, template strings.Builder
+
+	write := func(s string) {
+		value.WriteString(s)
+		template.WriteString(s)
+	}
+
+	for i := 0; i < len(body); i++ {
+		if body[i] != '\\' || i+1 >= len(body) {
+			write(body[i : i+1])
+			continue
+		}
+
+		i++
+		switch body[i] {
+		case 'n':
+			write("\n")
+		case 'r':
+			write("\r")
+		case 't':
+			write("\t")
+		case '"', '\\':
+			write(body[i : i+1])
+		case '$':
+			value.WriteByte('$')
+			template.WriteString("$$")
+		default:
+			write(body[i-1 : i+1])
+		}
+	}
+
+	return value.String(), template.String()
+}
+
+func inlineComment(rest string) string {
+	rest = strings.TrimSpace(rest)
+
+	if strings.HasPrefix(rest, "#") {
+		return rest
+	}
+
+	return ""
+}
+
+func splitInlineComment(rest string) (string, string) {
+	for i := 0; i < len(rest); i++ {
+		if rest[i] == '#' && (i == 0 || rest[i-1] == ' ' || rest[i-1] == '\t') {
+			return strings.TrimSpace(rest[:i]), rest[i:]
+		}
+	}
+
+	return strings.TrimSpace(rest), ""
+}
+
+func isValidKey(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	for i, c := range key {
+		switch {
+		case c == '_', c == '.', c == '-':
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// Lookup returns the effective assignment for key. When a key is assigned
+// more than once the last assignment wins, matching how the file is read.
+func (d *Document) Lookup(key string) (*Line, bool) {
+	for i := len(d.Lines) - 1; i >= 0; i-- {
+		if line := d.Lines[i]; line.Kind == LineAssignment && line.Key == key {
+			return line, true
+		}
+	}
+
+	return nil, false
+}
+
+// Positions maps variables to where they are assigned.
+type Positions map[string]schema.Position
+
+// Positions returns the position of the effective assignment of every key
+// that was parsed from the source.
+func (d *Document) Positions() Positions {
+	positions := make(Positions)
+
+	for _, line := range d.Lines {
+		if line.Kind == LineAssignment && line.Number > 0 {
+			positions[line.Key] = schema.Position{File: d.File, Line: line.Number, Column: line.Column}
+		}
+	}
+
+	return positions
+}
+
+// Template returns the value of an assignment in the syntax understood by
+// Expand.
+func (l *Line) Template() string {
+	return l.template
+}
+
+func (d *Document) Get(key string) (string, bool) {
+	line, ok := d.Lookup(key)
+
+	if !ok {
+		return "", false
+	}
+
+	return line.Value, true
+}
+
+// Set updates the effective assignment of key in place, keeping its quoting,
+// export prefix and inline comment, or appends a new assignment at the end of
+// the document.
+func (d *Document) Set(key, value string) {
+	if line, ok := d.Lookup(key); ok {
+		if line.Value != value {
+			line.Value = value
+			line.template = EscapeTemplate(value)
+			line.modified = true
+		}
+		return
+	}
+
+	d.Lines = append(d.Lines, &Line{
+		Kind:     LineAssignment,
+		Key:      key,
+		Value:    value,
+		template: EscapeTemplate(value),
+		modified: true,
+	})
+}
+
+// SetTemplate is Set for a value in the syntax understood by Expand, so that
+// references are written as references rather than as literal text.
+func (d *Document) SetTemplate(key, template string) {
+	value := strings.ReplaceAll(template, "$$", "$")
+
+	if line, ok := d.Lookup(key); ok {
+		if line.template != template {
+			line.Value = value
+			line.template = template
+			line.modified = true
+		}
+		return
+	}
+
+	d.Lines = append(d.Lines, &Line{
+		Kind:     LineAssignment,
+		Key:      key,
+		Value:    value,
+		template: template,
+		modified: true,
+	})
+}
+
+// Delete removes every assignment of key and reports whether any existed.
+func (d *Document) Delete(key string) bool {
+	kept := d.Lines[:0]
+	deleted := false
+
+	for _, line := range d.Lines {
+		if line.Kind == LineAssignment && line.Key == key {
+			deleted = true
+			continue
+		}
+
+		kept = append(kept, line)
+	}
+
+	d.Lines = kept
+	return deleted
+}
+
+func (d *Document) Keys() []string {
+	return d.Vars().Keys()
+}
+
+func (d *Document) Vars() Vars {
+	vars := make(Vars)
+
+	for _, line := range d.Lines {
+		if line.Kind == LineAssignment {
+			vars[line.Key] = line.Value
+		}
+	}
+
+	return vars
+}
+
+// Templates returns the effective values in the syntax understood by Expand,
+// so that escaped and single-quoted dollar signs stay literal.
+func (d *Document) Templates() Vars {
+	vars := make(Vars)
+
+	for _, line := range d.Lines {
+		if line.Kind == LineAssignment {
+			vars[line.Key] = line.template
+		}
+	}
+
+	return vars
+}
+
+// Err reports the first line that could not be parsed.
+func (d *Document) Err() error {
+	for _, line := range d.Lines {
+		if line.Kind == LineInvalid {
+			return fmt.Errorf("line %d: invalid assignment %q", line.Number, strings.TrimSpace(line.Raw))
+		}
+	}
+
+	return nil
+}
+
+func (d *Document) String() string {
+	var b strings.Builder
+
+	for i, line := range d.Lines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+
+		b.WriteString(line.String())
+	}
+
+	if len(d.Lines) > 0 && d.TrailingNewline {
+		b.WriteByte('\n')
+	}
+
+	if d.LineEnding == "\r\n" {
+		return strings.ReplaceAll(b.String(), "\n", "\r\n")
+	}
+
+	return b.String()
+}
+
+func (d *Document) WriteToFile(filename string) error {
+	mode := os.FileMode(0600)
+
+	if info, err := os.Stat(filename); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	return os.WriteFile(filename, []byte(d.String()), mode)
+}
+
+func (l *Line) String() string {
+	if l.Kind != LineAssignment || !l.modified {
+		return l.Raw
+	}
+
+	var b strings.Builder
+	b.WriteString(l.Indent)
+
+	if l.Export {
+		b.WriteString("export ")
+	}
+
+	b.WriteString(l.Key)
+	b.WriteByte('=')
+
+	switch {
+	case l.template == EscapeTemplate(l.Value):
+		b.WriteString(formatValue(l.Value, l.Quote))
+	case l.Quote == '"':
+		b.WriteString(doubleQuoteTemplate(l.template))
+	default:
+		// Single quotes cannot hold references.
+		b.WriteString(QuoteTemplate(l.template))
+	}
+
+	if l.Comment != "" {
+		b.WriteByte(' ')
+		b.WriteString(l.Comment)
+	}
+
+	return b.String()
+}
+
+// formatValue renders value with the requested quote style, falling back to
+// double quotes when the style cannot represent the value.
+func formatValue(value string, quote byte) string {
+	switch {
+	case quote == '\'' && !strings.Contains(value, "'"):
+		return "'" + value + "'"
+	case quote == '"', quote == '\'':
+		return doubleQuote(value)
+	case value == "" || !needsQuoting(value):
+		return value
+	default:
+		return doubleQuote(value)
+	}
+}
+
+func needsQuoting(value string) bool {
+	return strings.ContainsAny(value, " \t\n\r\"'#$\\`") || value != strings.TrimSpace(value)
+}
+
+func doubleQuote(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"\n", `\n`,
+		"\r", `\r`,
+		"$", `\$`,
+	)
+
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// QuoteTemplate renders a value in the syntax understood by Expand as it is
+// written in an env file: unquoted when nothing in it needs quotes and
+// double-quoted otherwise.
+func QuoteTemplate(template string) string {
+	if !strings.ContainsAny(template, " \t\n\r\"'#\\`") && !strings.Contains(template, "$$") {
+		return template
+	}
+
+	return doubleQuoteTemplate(template)
+}
+
+func doubleQuoteTemplate(template string) string {
+	replacer := strings.NewReplacer(
+		`$$`, `\$`,
+		`\`, `\\`,
+		`"`, `\"`,
+		"\n", `\n`,
+		"\r", `\r`,
+		"\t", `\t`,
+	)
+
+	return `"` + replacer.Replace(template) + `"`
+}
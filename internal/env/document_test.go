@@ -0,0 +1,180 @@
+package env_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tommyalmeida/envsync/internal/config"
+	"github.com/tommyalmeida/envsync/internal/env"
+)
+
+const curatedEnv = `# Database settings
+export DATABASE_URL="postgres://localhost/app" # primary
+
+  # Cache
+REDIS_URL='redis://localhost:6379'
+MESSAGE="multi
+line"
+PORT=3000 # http port
+not a valid line
+`
+
+func TestParseDocument_RoundTrip(t *testing.T) {
+	inputs := []string{
+		curatedEnv,
+		strings.ReplaceAll(curatedEnv, "\n", "\r\n"),
+		"KEY=value",
+		"KEY=value\r\n",
+		"",
+		"\n\n# only comments\n",
+	}
+
+	for _, input := range inputs {
+		doc, err := env.ParseDocument(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got := doc.String(); got != input {
+			t.Errorf("round trip mismatch:\nexpected %q\ngot      %q", input, got)
+		}
+	}
+}
+
+func TestParseDocument_Values(t *testing.T) {
+	doc, err := env.ParseDocument(strings.NewReader(curatedEnv))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := env.Vars{
+		"DATABASE_URL": "postgres://localhost/app",
+		"REDIS_URL":    "redis://localhost:6379",
+		"MESSAGE":      "multi\nline",
+		"PORT":         "3000",
+	}
+
+	if !reflect.DeepEqual(doc.Vars(), expected) {
+		t.Errorf("expected %+v, got %+v", expected, doc.Vars())
+	}
+
+	line, ok := doc.Lookup("DATABASE_URL")
+	if !ok || !line.Export || line.Quote != '"' || line.Comment != "# primary" {
+		t.Errorf("expected export, double quotes and comment to be kept, got %+v", line)
+	}
+}
+
+func TestDocument_Positions(t *testing.T) {
+	doc, err := env.ParseDocument(strings.NewReader(curatedEnv + "PORT=8080\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	doc.File = ".env"
+	doc.Set("NEW", "value")
+
+	expected := env.Positions{
+		"DATABASE_URL": {File: ".env", Line: 2, Column: 8},
+		"REDIS_URL":    {File: ".env", Line: 5, Column: 1},
+		"MESSAGE":      {File: ".env", Line: 6, Column: 1},
+		"PORT":         {File: ".env", Line: 10, Column: 1},
+	}
+
+	if got := doc.Positions(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	line, _ := doc.Lookup("MESSAGE")
+	if line.Raw != "MESSAGE=\"multi\nline\"" {
+		t.Errorf("expected the raw text of both lines, got %q", line.Raw)
+	}
+}
+
+func TestDocument_SetKeepsFormatting(t *testing.T) {
+	doc, err := env.ParseDocument(strings.NewReader(curatedEnv))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	doc.Set("DATABASE_URL", "postgres://db/app")
+	doc.Set("NEW_KEY", "hello world")
+
+	expected := strings.Replace(curatedEnv,
+		`export DATABASE_URL="postgres://localhost/app" # primary`,
+		`export DATABASE_URL="postgres://db/app" # primary`, 1) + `NEW_KEY="hello world"` + "\n"
+
+	if got := doc.String(); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestDocument_SetKeepsCRLF(t *testing.T) {
+	crlf := strings.ReplaceAll(curatedEnv, "\n", "\r\n")
+
+	doc, err := env.ParseDocument(strings.NewReader(crlf))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := doc.Vars()["MESSAGE"]; got != "multi\nline" {
+		t.Errorf("expected MESSAGE to be %q, got %q", "multi\nline", got)
+	}
+
+	doc.Set("PORT", "8080")
+	doc.Set("NEW_KEY", "hello")
+
+	expected := strings.Replace(crlf, "PORT=3000 # http port", "PORT=8080 # http port", 1) + "NEW_KEY=hello\r\n"
+
+	if got := doc.String(); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestDocument_Delete(t *testing.T) {
+	doc, err := env.ParseDocument(strings.NewReader("A=1\n# keep\nB=2\nA=3\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !doc.Delete("A") {
+		t.Fatal("expected A to be deleted")
+	}
+
+	if got := doc.String(); got != "# keep\nB=2\n" {
+		t.Errorf("unexpected document after delete: %q", got)
+	}
+}
+
+func TestSyncer_SyncPreservesTarget(t *testing.T) {
+	targetFile := filepath.Join(t.TempDir(), ".env")
+	curated := strings.Replace(curatedEnv, "not a valid line\n", "", 1)
+
+	if err := os.WriteFile(targetFile, []byte(curated), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	target, err := env.ParseFile(targetFile)
+	if err != nil {
+		t.Fatalf("failed to parse target: %v", err)
+	}
+
+	source := env.Vars{"PORT": "8080", "API_KEY": "secret", "LOG_LEVEL": "debug"}
+	cfg := &config.Config{}
+
+	if _, err := env.NewSyncer(cfg).Sync(source, target, targetFile, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(targetFile)
+	if err != nil {
+		t.Fatalf("failed to read target: %v", err)
+	}
+
+	expected := curated + "API_KEY=secret\nLOG_LEVEL=debug\n"
+	if string(content) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, content)
+	}
+}
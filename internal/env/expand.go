@@ -0,0 +1,343 @@
+package env
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// ExpandOptions controls how references are resolved.
+type ExpandOptions struct {
+	// ProcessEnv resolves references to variables that are not defined in the
+	// files from the process environment.
+	ProcessEnv bool
+}
+
+// DependencyGraph maps each variable to the sorted names it references.
+// Variables without references are left out.
+type DependencyGraph map[string][]string
+
+// CycleError reports a chain of references that leads back to its start.
+type CycleError struct {
+	Cycle []string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("reference cycle: %s", strings.Join(e.Cycle, " -> "))
+}
+
+// Expand resolves ${VAR}, $VAR, ${VAR:-default}, ${VAR-default},
+// ${VAR:?error} and ${VAR?error} references in raw values, such as those
+// returned by ParseFileRaw or merged from several of them. References are
+// resolved against the other values regardless of their order, and $$ is a
+// literal dollar sign. Unset references without a default expand to "".
+func Expand(raw Vars, opts ExpandOptions) (Vars, error) {
+	e := &expander{
+		raw:      raw,
+		opts:     opts,
+		resolved: make(Vars, len(raw)),
+		visiting: make(map[string]bool),
+	}
+
+	for _, key := range raw.Keys() {
+		if _, _, err := e.resolve(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return e.resolved, nil
+}
+
+// References returns the dependency graph of raw values without resolving
+// them. References inside defaults are included.
+func References(raw Vars) (DependencyGraph, error) {
+	graph := make(DependencyGraph)
+
+	for key, value := range raw {
+		segments, err := parseTemplate(value)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+
+		names := make(map[string]bool)
+		collectReferences(segments, names)
+
+		if len(names) > 0 {
+			graph[key] = slices.Sorted(maps.Keys(names))
+		}
+	}
+
+	return graph, nil
+}
+
+// EscapeTemplate returns value as a raw value that expands to itself.
+func EscapeTemplate(value string) string {
+	return strings.ReplaceAll(value, "$", "$$")
+}
+
+// EscapeTemplates returns vars as raw values that expand to themselves, for
+// values that come from somewhere references are not supported.
+func EscapeTemplates(vars Vars) Vars {
+	escaped := make(Vars, len(vars))
+
+	for key, value := range vars {
+		escaped[key] = EscapeTemplate(value)
+	}
+
+	return escaped
+}
+
+type expander struct {
+	raw      Vars
+	opts     ExpandOptions
+	resolved Vars
+	visiting map[string]bool
+	stack    []string
+}
+
+func (e *expander) resolve(key string) (string, bool, error) {
+	if value, exists := e.resolved[key]; exists {
+		return value, true, nil
+	}
+
+	template, exists := e.raw[key]
+
+	if !exists {
+		if e.opts.ProcessEnv {
+			value, exists := os.LookupEnv(key)
+			return value, exists, nil
+		}
+
+		return "", false, nil
+	}
+
+	if e.visiting[key] {
+		start := 0
+		for i, name := range e.stack {
+			if name == key {
+				start = i
+				break
+			}
+		}
+
+		cycle := append(append([]string{}, e.stack[start:]...), key)
+		return "", false, &CycleError{Cycle: cycle}
+	}
+
+	segments, err := parseTemplate(template)
+	if err != nil {
+		return "", false, fmt.Errorf("%s: %w", key, err)
+	}
+
+	e.visiting[key] = true
+	e.stack = append(e.stack, key)
+
+	value, err := e.expand(key, segments)
+
+	e.stack = e.stack[:len(e.stack)-1]
+	delete(e.visiting, key)
+
+	if err != nil {
+		return "", false, err
+	}
+
+	e.resolved[key] = value
+
+	return value, true, nil
+}
+
+func (e *expander) expand(key string, segments []segment) (string, error) {
+	var b strings.Builder
+
+	for _, seg := range segments {
+		if seg.name == "" {
+			b.WriteString(seg.literal)
+			continue
+		}
+
+		value, exists, err := e.resolve(seg.name)
+
+		if err != nil {
+			return "", err
+		}
+
+		unset := !exists || (seg.colon && value == "")
+
+		switch {
+		case seg.op == '-' && unset:
+			value, err = e.expand(key, seg.operand)
+
+			if err != nil {
+				return "", err
+			}
+		case seg.op == '?' && unset:
+			message, err := e.expand(key, seg.operand)
+
+			if err != nil {
+				return "", err
+			}
+
+			if message == "" {
+				message = "is not set"
+			}
+
+			return "", fmt.Errorf("%s references %s: %s", key, seg.name, message)
+		}
+
+		b.WriteString(value)
+	}
+
+	return b.String(), nil
+}
+
+// segment is either a literal or a reference to name with an optional
+// operator and operand, as in ${name:-operand}.
+type segment struct {
+	literal string
+	name    string
+	op      byte
+	colon   bool
+	operand []segment
+}
+
+func collectReferences(segments []segment, names map[string]bool) {
+	for _, seg := range segments {
+		if seg.name != "" {
+			names[seg.name] = true
+			collectReferences(seg.operand, names)
+		}
+	}
+}
+
+func parseTemplate(s string) ([]segment, error) {
+	segments, rest, err := parseSegments(s, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if rest != "" {
+		return nil, fmt.Errorf("unexpected %q", rest)
+	}
+
+	return segments, nil
+}
+
+// parseSegments parses s until its end or, inside an operand, until the
+// closing brace, and returns the unparsed remainder.
+func parseSegments(s string, inOperand bool) ([]segment, string, error) {
+	var segments []segment
+	var literal strings.Builder
+
+	flush := func() {
+		if literal.Len() > 0 {
+			segments = append(segments, segment{literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for len(s) > 0 {
+		c := s[0]
+
+		if inOperand && c == '}' {
+			break
+		}
+
+		if c != '$' || len(s) == 1 {
+			literal.WriteByte(c)
+			s = s[1:]
+			continue
+		}
+
+		switch next := s[1]; {
+		case next == '$':
+			literal.WriteByte('$')
+			s = s[2:]
+		case next == '{':
+			ref, rest, err := parseBraced(s[2:])
+
+			if err != nil {
+				return nil, "", err
+			}
+
+			flush()
+			segments = append(segments, ref)
+			s = rest
+		case isNameStart(next):
+			end := 2
+			for end < len(s) && isNameChar(s[end]) {
+				end++
+			}
+
+			flush()
+			segments = append(segments, segment{name: s[1:end]})
+			s = s[end:]
+		default:
+			literal.WriteByte(c)
+			s = s[1:]
+		}
+	}
+
+	flush()
+
+	return segments, s, nil
+}
+
+func parseBraced(s string) (segment, string, error) {
+	end := 0
+	for end < len(s) && isNameChar(s[end]) {
+		end++
+	}
+
+	if end == 0 || !isNameStart(s[0]) {
+		return segment{}, "", fmt.Errorf("invalid reference ${%s", truncate(s))
+	}
+
+	seg := segment{name: s[:end]}
+	s = s[end:]
+
+	if strings.HasPrefix(s, ":") {
+		seg.colon = true
+		s = s[1:]
+	}
+
+	if len(s) > 0 && (s[0] == '-' || s[0] == '?') {
+		seg.op = s[0]
+
+		operand, rest, err := parseSegments(s[1:], true)
+
+		if err != nil {
+			return segment{}, "", err
+		}
+
+		seg.operand = operand
+		s = rest
+	} else if seg.colon {
+		return segment{}, "", fmt.Errorf("unsupported operator in ${%s", truncate(seg.name+":"+s))
+	}
+
+	if !strings.HasPrefix(s, "}") {
+		return segment{}, "", fmt.Errorf("unterminated reference ${%s", truncate(seg.name))
+	}
+
+	return seg, s[1:], nil
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
+
+func truncate(s string) string {
+	if len(s) > 20 {
+		return s[:20] + "…"
+	}
+
+	return s
+}
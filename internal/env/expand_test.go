@@ -0,0 +1,150 @@
+package env_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tommyalmeida/envsync/internal/env"
+)
+
+func TestExpand(t *testing.T) {
+	t.Setenv("ENVSYNC_TEST_HOME", "/home/test")
+
+	tests := []struct {
+		name     string
+		raw      env.Vars
+		opts     env.ExpandOptions
+		expected env.Vars
+	}{
+		{
+			name: "references in any order",
+			raw: env.Vars{
+				"URL":  "http://${HOST}:$PORT/api",
+				"HOST": "localhost",
+				"PORT": "8080",
+			},
+			expected: env.Vars{
+				"URL":  "http://localhost:8080/api",
+				"HOST": "localhost",
+				"PORT": "8080",
+			},
+		},
+		{
+			name: "defaults",
+			raw: env.Vars{
+				"EMPTY":     "",
+				"UNSET_DEF": "${MISSING:-fallback}",
+				"EMPTY_DEF": "${EMPTY:-fallback}",
+				"EMPTY_SET": "${EMPTY-fallback}",
+				"NESTED":    "${MISSING:-${UNSET_DEF}-x}",
+			},
+			expected: env.Vars{
+				"EMPTY":     "",
+				"UNSET_DEF": "fallback",
+				"EMPTY_DEF": "fallback",
+				"EMPTY_SET": "",
+				"NESTED":    "fallback-x",
+			},
+		},
+		{
+			name:     "escaped dollar and unset references",
+			raw:      env.Vars{"PRICE": "$$5 ${MISSING}$"},
+			expected: env.Vars{"PRICE": "$5 $"},
+		},
+		{
+			name:     "process environment is ignored by default",
+			raw:      env.Vars{"DIR": "${ENVSYNC_TEST_HOME}/app"},
+			expected: env.Vars{"DIR": "/app"},
+		},
+		{
+			name:     "process environment",
+			raw:      env.Vars{"DIR": "${ENVSYNC_TEST_HOME}/app"},
+			opts:     env.ExpandOptions{ProcessEnv: true},
+			expected: env.Vars{"DIR": "/home/test/app"},
+		},
+		{
+			name:     "files take precedence over the process environment",
+			raw:      env.Vars{"ENVSYNC_TEST_HOME": "/srv", "DIR": "${ENVSYNC_TEST_HOME}/app"},
+			opts:     env.ExpandOptions{ProcessEnv: true},
+			expected: env.Vars{"ENVSYNC_TEST_HOME": "/srv", "DIR": "/srv/app"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := env.Expand(tt.raw, tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestExpand_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    env.Vars
+		errMsg string
+	}{
+		{"required with message", env.Vars{"DSN": "${DB_HOST:?must be set}"}, "DSN references DB_HOST: must be set"},
+		{"required without message", env.Vars{"DSN": "${DB_HOST?}"}, "DSN references DB_HOST: is not set"},
+		{"unterminated reference", env.Vars{"A": "${B"}, "unterminated reference"},
+		{"invalid reference", env.Vars{"A": "${1B}"}, "invalid reference"},
+		{"self reference", env.Vars{"A": "$A"}, "reference cycle: A -> A"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := env.Expand(tt.raw, env.ExpandOptions{})
+
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestExpand_Cycle(t *testing.T) {
+	raw := env.Vars{
+		"A": "${B}",
+		"B": "${C:-x}",
+		"C": "prefix-$A",
+		"D": "independent",
+	}
+
+	_, err := env.Expand(raw, env.ExpandOptions{})
+
+	var cycleErr *env.CycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("expected a CycleError, got %v", err)
+	}
+
+	expected := []string{"A", "B", "C", "A"}
+	if !reflect.DeepEqual(cycleErr.Cycle, expected) {
+		t.Errorf("expected cycle %v, got %v", expected, cycleErr.Cycle)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	raw := env.Vars{
+		"URL":   "http://${HOST}:${PORT:-${DEFAULT_PORT}}",
+		"HOST":  "localhost",
+		"PRICE": "$$5",
+	}
+
+	graph, err := env.References(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := env.DependencyGraph{"URL": {"DEFAULT_PORT", "HOST", "PORT"}}
+	if !reflect.DeepEqual(graph, expected) {
+		t.Errorf("expected %+v, got %+v", expected, graph)
+	}
+}
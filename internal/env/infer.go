@@ -0,0 +1,108 @@
+package env
+
+import (
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/tommyalmeida/envsync/pkg/schema"
+)
+
+// InferOptions tunes schema inference.
+type InferOptions struct {
+	// IsSecret reports whether a variable is secret by its name.
+	IsSecret func(key string) bool
+}
+
+// InferSchema guesses a schema from example env files. Variables set to a
+// non-empty value in every file are required, types are the most specific type all values
+// satisfy, and variables are secret when IsSecret says so or any value looks
+// like a random token.
+func InferSchema(files []Vars, opts InferOptions) schema.Schema {
+	s := schema.Schema{Variables: make(map[string]schema.Variable)}
+
+	for _, key := range Merge(files...).Keys() {
+		var values []string
+		set := 0
+
+		for _, vars := range files {
+			if value, exists := vars[key]; exists {
+				values = append(values, value)
+
+				// Validation rejects empty values of required variables.
+				if value != "" {
+					set++
+				}
+			}
+		}
+
+		variable := schema.Variable{
+			Required: set == len(files),
+			Type:     schema.InferType(values),
+		}
+
+		// Ports are integers too, so only names tell them apart: a PORT word
+		// in the name, but not SUPPORT or PORTAL.
+		if variable.Type == "integer" && slices.Contains(strings.Split(key, "_"), "PORT") && satisfies(schema.Variable{Type: "port"}, values) {
+			variable.Type = "port"
+		}
+
+		if variable.Type == "string" {
+			variable.Type = ""
+		}
+
+		if opts.IsSecret != nil && opts.IsSecret(key) {
+			variable.Secret = true
+		}
+
+		for _, value := range values {
+			if variable.Type == "" && looksRandom(value) {
+				variable.Secret = true
+			}
+		}
+
+		s.Variables[key] = variable
+	}
+
+	return s
+}
+
+func satisfies(variable schema.Variable, values []string) bool {
+	s := schema.Schema{Variables: map[string]schema.Variable{"": variable}}
+
+	for _, value := range values {
+		if len(s.ValidateVariable("", value)) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// looksRandom reports whether value is long and varied enough to be a
+// generated credential rather than a word, path or sentence.
+func looksRandom(value string) bool {
+	if len(value) < 20 || strings.ContainsAny(value, " \t") {
+		return false
+	}
+
+	return entropy(value) >= 3.5
+}
+
+// entropy returns the Shannon entropy of s in bits per byte.
+func entropy(s string) float64 {
+	counts := make(map[byte]int)
+
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+
+	var bits float64
+
+	for _, count := range counts {
+		p := float64(count) / float64(len(s))
+		bits -= p * math.Log2(p)
+	}
+
+	return bits
+}
@@ -0,0 +1,65 @@
+package env_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tommyalmeida/envsync/internal/env"
+	"github.com/tommyalmeida/envsync/pkg/schema"
+)
+
+func TestInferSchema(t *testing.T) {
+	files := []env.Vars{
+		{
+			"PORT":         "3000",
+			"REDIS_PORT":   "6379",
+			"SUPPORT_SEAT": "12",
+			"WORKERS":      "4",
+			"DEBUG":        "true",
+			"DATABASE_URL": "postgres://db/app",
+			"SESSION":      "Zx8Kq2LmP9vR4tW7yB1nC5dF",
+			"API_TOKEN":    "abc",
+			"NAME":         "my app",
+			"EMPTY":        "",
+		},
+		{
+			"PORT":         "4000",
+			"REDIS_PORT":   "6380",
+			"SUPPORT_SEAT": "30",
+			"WORKERS":      "8",
+			"DEBUG":        "false",
+			"DATABASE_URL": "postgres://db2/app",
+			"SESSION":      "Qw3Er5Ty7Ui9Op1As2Df4Gh6",
+			"NAME":         "",
+			"EMPTY":        "",
+		},
+	}
+
+	isSecret := func(key string) bool { return strings.HasSuffix(key, "_TOKEN") }
+
+	got := env.InferSchema(files, env.InferOptions{IsSecret: isSecret})
+
+	expected := map[string]schema.Variable{
+		"PORT":         {Required: true, Type: "port"},
+		"REDIS_PORT":   {Required: true, Type: "port"},
+		"SUPPORT_SEAT": {Required: true, Type: "integer"},
+		"WORKERS":      {Required: true, Type: "integer"},
+		"DEBUG":        {Required: true, Type: "boolean"},
+		"DATABASE_URL": {Required: true, Type: "url"},
+		"SESSION":      {Required: true, Secret: true},
+		"API_TOKEN":    {Secret: true},
+		"NAME":         {},
+		"EMPTY":        {},
+	}
+
+	if !reflect.DeepEqual(got.Variables, expected) {
+		t.Errorf("expected %v, got %v", expected, got.Variables)
+	}
+
+	for i, vars := range files {
+		if result := env.NewValidator(got).Validate(vars); !result.Valid {
+			t.Errorf("file %d is invalid against the inferred schema: %+v", i, result)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package env
+
+import (
+	"crypto/sha256"
+	"maps"
+	"slices"
+)
+
+// MatrixResult is a key × environment comparison of several env files.
+type MatrixResult struct {
+	Environments []string    `json:"environments"`
+	Rows         []MatrixRow `json:"rows"`
+	Ignored      []string    `json:"ignored,omitempty"`
+	Rules        []RuleMatch `json:"rules,omitempty"`
+}
+
+// MatrixRow holds one cell per environment, in the order of
+// MatrixResult.Environments.
+type MatrixRow struct {
+	Key        string       `json:"key"`
+	Cells      []MatrixCell `json:"cells"`
+	Consistent bool         `json:"consistent"`
+}
+
+// MatrixCell tells whether a key is present in an environment and, if so,
+// which value group it belongs to. Environments whose values hash the same
+// share a group; groups are labelled A, B, C… in order of first appearance,
+// so values themselves never appear in the matrix.
+type MatrixCell struct {
+	Present bool   `json:"present"`
+	Group   string `json:"group,omitempty"`
+}
+
+func CompareMany(names []string, envs []Vars) MatrixResult {
+	return CompareManyWithRules(names, envs, nil)
+}
+
+// CompareManyWithRules compares envs, named by names, after dropping every key
+// matched by the rule set's ignore patterns.
+func CompareManyWithRules(names []string, envs []Vars, rules *RuleSet) MatrixResult {
+	result := MatrixResult{Environments: names}
+
+	filtered := make([]Vars, len(envs))
+	keys := make(map[string]bool)
+	ignored := make(map[string]RuleMatch)
+
+	for i, vars := range envs {
+		var matches []RuleMatch
+		filtered[i], matches = rules.Filter(vars)
+
+		for _, match := range matches {
+			ignored[match.Variable] = match
+		}
+
+		for key := range filtered[i] {
+			keys[key] = true
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(ignored)) {
+		result.Ignored = append(result.Ignored, key)
+		result.Rules = append(result.Rules, ignored[key])
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(keys)) {
+		result.Rows = append(result.Rows, compareKey(key, filtered))
+	}
+
+	return result
+}
+
+func compareKey(key string, envs []Vars) MatrixRow {
+	row := MatrixRow{Key: key, Cells: make([]MatrixCell, len(envs))}
+	groups := make(map[[sha256.Size]byte]string)
+
+	for i, vars := range envs {
+		value, exists := vars[key]
+
+		if !exists {
+			continue
+		}
+
+		hash := sha256.Sum256([]byte(value))
+		group, seen := groups[hash]
+
+		if !seen {
+			group = groupLabel(len(groups))
+			groups[hash] = group
+		}
+
+		row.Cells[i] = MatrixCell{Present: true, Group: group}
+	}
+
+	row.Consistent = len(groups) == 1
+	for _, cell := range row.Cells {
+		if !cell.Present {
+			row.Consistent = false
+		}
+	}
+
+	return row
+}
+
+// groupLabel returns A…Z, then AA, AB… for larger indexes.
+func groupLabel(i int) string {
+	var label string
+
+	for n := i + 1; n > 0; n /= 26 {
+		n--
+		label = string(rune('A'+n%26)) + label
+	}
+
+	return label
+}
+
+// Inconsistent returns the rows whose key is missing somewhere or has more
+// than one value.
+func (m MatrixResult) Inconsistent() []MatrixRow {
+	var rows []MatrixRow
+
+	for _, row := range m.Rows {
+		if !row.Consistent {
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
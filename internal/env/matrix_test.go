@@ -0,0 +1,82 @@
+package env_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tommyalmeida/envsync/internal/config"
+	"github.com/tommyalmeida/envsync/internal/env"
+)
+
+func TestCompareMany(t *testing.T) {
+	names := []string{"dev", "staging", "prod"}
+	envs := []env.Vars{
+		{"PORT": "3000", "DEBUG": "true", "API_KEY": "dev-key", "TEMP_DIR": "/tmp"},
+		{"PORT": "3000", "API_KEY": "staging-key"},
+		{"PORT": "3000", "DEBUG": "false", "API_KEY": "dev-key"},
+	}
+
+	rules, err := env.NewRuleSet(config.Rules{IgnorePatterns: []string{"^TEMP_"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := env.CompareManyWithRules(names, envs, rules)
+
+	expected := []env.MatrixRow{
+		{
+			Key: "API_KEY",
+			Cells: []env.MatrixCell{
+				{Present: true, Group: "A"},
+				{Present: true, Group: "B"},
+				{Present: true, Group: "A"},
+			},
+		},
+		{
+			Key: "DEBUG",
+			Cells: []env.MatrixCell{
+				{Present: true, Group: "A"},
+				{},
+				{Present: true, Group: "B"},
+			},
+		},
+		{
+			Key: "PORT",
+			Cells: []env.MatrixCell{
+				{Present: true, Group: "A"},
+				{Present: true, Group: "A"},
+				{Present: true, Group: "A"},
+			},
+			Consistent: true,
+		},
+	}
+
+	if !reflect.DeepEqual(result.Rows, expected) {
+		t.Errorf("expected rows %+v, got %+v", expected, result.Rows)
+	}
+
+	if !reflect.DeepEqual(result.Ignored, []string{"TEMP_DIR"}) {
+		t.Errorf("expected TEMP_DIR to be ignored, got %v", result.Ignored)
+	}
+
+	if len(result.Inconsistent()) != 2 {
+		t.Errorf("expected 2 inconsistent rows, got %d", len(result.Inconsistent()))
+	}
+}
+
+func TestCompareMany_ManyGroups(t *testing.T) {
+	envs := make([]env.Vars, 28)
+	names := make([]string, len(envs))
+
+	for i := range envs {
+		envs[i] = env.Vars{"KEY": string(rune('a' + i))}
+		names[i] = string(rune('a' + i))
+	}
+
+	result := env.CompareMany(names, envs)
+	cells := result.Rows[0].Cells
+
+	if cells[0].Group != "A" || cells[25].Group != "Z" || cells[26].Group != "AA" || cells[27].Group != "AB" {
+		t.Errorf("unexpected group labels: %+v", cells)
+	}
+}
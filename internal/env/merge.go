@@ -0,0 +1,215 @@
+package env
+
+// Merge sides, as used in MergeChange and conflict markers.
+const (
+	SideOurs   = "ours"
+	SideTheirs = "theirs"
+	SideBoth   = "both"
+)
+
+// MergeResult is the outcome of a three-way merge.
+type MergeResult struct {
+	Vars      Vars            `json:"-"`
+	Changes   []MergeChange   `json:"changes"`
+	Conflicts []MergeConflict `json:"conflicts"`
+}
+
+// MergeChange is a change to the base that the merge applied.
+type MergeChange struct {
+	Variable string `json:"variable"`
+	Side     string `json:"side"`
+	Action   string `json:"action"`
+}
+
+// MergeConflict is a variable both sides changed differently. A nil value
+// means the variable does not exist on that side.
+type MergeConflict struct {
+	Variable string  `json:"variable"`
+	Base     *string `json:"base"`
+	Ours     *string `json:"ours"`
+	Theirs   *string `json:"theirs"`
+}
+
+// ThreeWayMerge applies the changes ours and theirs made to base. A variable
+// changed on one side takes that side's value, or is removed if that side
+// deleted it. A variable both sides changed the same way is merged too;
+// otherwise it conflicts and keeps our value in Vars.
+func ThreeWayMerge(base, ours, theirs Vars) MergeResult {
+	result := MergeResult{Vars: make(Vars)}
+
+	oursDiff := CompareEnvs(base, ours)
+	theirsDiff := CompareEnvs(base, theirs)
+
+	oursChanged := changedKeys(oursDiff)
+	theirsChanged := changedKeys(theirsDiff)
+
+	for _, key := range Merge(base, ours, theirs).Keys() {
+		oursAction, inOurs := oursChanged[key]
+		theirsAction, inTheirs := theirsChanged[key]
+
+		oursValue, oursExists := ours[key]
+		theirsValue, theirsExists := theirs[key]
+
+		switch {
+		case !inOurs && !inTheirs:
+			result.Vars[key] = base[key]
+		case inOurs && !inTheirs:
+			result.apply(key, SideOurs, oursAction, oursValue, oursExists)
+		case !inOurs && inTheirs:
+			result.apply(key, SideTheirs, theirsAction, theirsValue, theirsExists)
+		case oursExists == theirsExists && oursValue == theirsValue:
+			result.apply(key, SideBoth, oursAction, oursValue, oursExists)
+		default:
+			result.Conflicts = append(result.Conflicts, MergeConflict{
+				Variable: key,
+				Base:     lookup(base, key),
+				Ours:     lookup(ours, key),
+				Theirs:   lookup(theirs, key),
+			})
+
+			if oursExists {
+				result.Vars[key] = oursValue
+			}
+		}
+	}
+
+	return result
+}
+
+func (r *MergeResult) apply(key, side, action, value string, exists bool) {
+	if exists {
+		r.Vars[key] = value
+	}
+
+	r.Changes = append(r.Changes, MergeChange{Variable: key, Side: side, Action: action})
+}
+
+// changedKeys maps every key a side added, updated or removed to the action.
+func changedKeys(diff DiffResult) map[string]string {
+	changed := make(map[string]string)
+
+	for _, key := range diff.Missing {
+		changed[key] = ActionRemoved
+	}
+
+	for _, key := range diff.Extra {
+		changed[key] = ActionAdded
+	}
+
+	for key := range diff.Different {
+		changed[key] = ActionUpdated
+	}
+
+	return changed
+}
+
+func lookup(vars Vars, key string) *string {
+	if value, exists := vars[key]; exists {
+		return &value
+	}
+
+	return nil
+}
+
+// MergeDocuments merges the values of three documents and returns the result
+// as an edit of ours, so our comments, ordering and quoting are kept.
+// Variables added by theirs are appended with their original formatting, and
+// conflicts are written as conflict markers in place of our assignment.
+func MergeDocuments(base, ours, theirs *Document) (*Document, MergeResult) {
+	result := ThreeWayMerge(base.Vars(), ours.Vars(), theirs.Vars())
+
+	doc := &Document{TrailingNewline: ours.TrailingNewline || len(ours.Lines) == 0, LineEnding: ours.LineEnding}
+	for _, line := range ours.Lines {
+		copied := *line
+		doc.Lines = append(doc.Lines, &copied)
+	}
+
+	conflicts := make(map[string]MergeConflict, len(result.Conflicts))
+	for _, conflict := range result.Conflicts {
+		conflicts[conflict.Variable] = conflict
+	}
+
+	for _, key := range ours.Keys() {
+		if conflict, exists := conflicts[key]; exists {
+			doc.replace(key, conflictLine(conflict))
+			continue
+		}
+
+		value, exists := result.Vars[key]
+
+		if !exists {
+			doc.Delete(key)
+			continue
+		}
+
+		doc.Set(key, value)
+	}
+
+	for _, line := range theirs.Lines {
+		if line.Kind != LineAssignment {
+			continue
+		}
+
+		if _, inOurs := ours.Lookup(line.Key); inOurs {
+			continue
+		}
+
+		if conflict, exists := conflicts[line.Key]; exists {
+			doc.Lines = append(doc.Lines, conflictLine(conflict))
+			delete(conflicts, line.Key)
+			continue
+		}
+
+		if effective, _ := theirs.Lookup(line.Key); effective != line {
+			continue
+		}
+
+		if _, exists := result.Vars[line.Key]; exists {
+			added := *line
+			doc.Lines = append(doc.Lines, &added)
+		}
+	}
+
+	return doc, result
+}
+
+// replace swaps the effective assignment of key for line and drops any
+// earlier assignments of it.
+func (d *Document) replace(key string, line *Line) {
+	effective, _ := d.Lookup(key)
+	lines := make([]*Line, 0, len(d.Lines))
+
+	for _, l := range d.Lines {
+		switch {
+		case l == effective:
+			lines = append(lines, line)
+		case l.Kind == LineAssignment && l.Key == key:
+		default:
+			lines = append(lines, l)
+		}
+	}
+
+	d.Lines = lines
+}
+
+// conflictLine renders a conflict in the diff3 style of git. The markers make
+// the document invalid until the conflict is resolved.
+func conflictLine(conflict MergeConflict) *Line {
+	side := func(value *string) string {
+		if value == nil {
+			return ""
+		}
+
+		return conflict.Variable + "=" + formatValue(*value, 0) + "\n"
+	}
+
+	raw := "<<<<<<< " + SideOurs + "\n" +
+		side(conflict.Ours) +
+		"||||||| base\n" +
+		side(conflict.Base) +
+		"=======\n" +
+		side(conflict.Theirs) +
+		">>>>>>> " + SideTheirs
+
+	return &Line{Kind: LineInvalid, Raw: raw}
+}
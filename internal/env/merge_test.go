@@ -0,0 +1,93 @@
+package env_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tommyalmeida/envsync/internal/env"
+)
+
+func TestThreeWayMerge(t *testing.T) {
+	base := env.Vars{"KEEP": "1", "OURS": "1", "THEIRS": "1", "BOTH": "1", "DELETED": "1", "CONFLICT": "1", "EDIT_DELETE": "1", "GONE": "1"}
+	ours := env.Vars{"KEEP": "1", "OURS": "2", "THEIRS": "1", "BOTH": "2", "CONFLICT": "ours", "ADDED": "x"}
+	theirs := env.Vars{"KEEP": "1", "OURS": "1", "THEIRS": "2", "BOTH": "2", "CONFLICT": "theirs", "EDIT_DELETE": "2", "GONE": "1", "ADDED": "x"}
+
+	result := env.ThreeWayMerge(base, ours, theirs)
+
+	expectedVars := env.Vars{"KEEP": "1", "OURS": "2", "THEIRS": "2", "BOTH": "2", "CONFLICT": "ours", "ADDED": "x"}
+	if !reflect.DeepEqual(result.Vars, expectedVars) {
+		t.Errorf("expected vars %v, got %v", expectedVars, result.Vars)
+	}
+
+	expectedChanges := []env.MergeChange{
+		{Variable: "ADDED", Side: env.SideBoth, Action: env.ActionAdded},
+		{Variable: "BOTH", Side: env.SideBoth, Action: env.ActionUpdated},
+		{Variable: "DELETED", Side: env.SideBoth, Action: env.ActionRemoved},
+		{Variable: "GONE", Side: env.SideOurs, Action: env.ActionRemoved},
+		{Variable: "OURS", Side: env.SideOurs, Action: env.ActionUpdated},
+		{Variable: "THEIRS", Side: env.SideTheirs, Action: env.ActionUpdated},
+	}
+	if !reflect.DeepEqual(result.Changes, expectedChanges) {
+		t.Errorf("expected changes %v, got %v", expectedChanges, result.Changes)
+	}
+
+	if len(result.Conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts, got %v", result.Conflicts)
+	}
+
+	conflict := result.Conflicts[0]
+	if conflict.Variable != "CONFLICT" || *conflict.Base != "1" || *conflict.Ours != "ours" || *conflict.Theirs != "theirs" {
+		t.Errorf("unexpected conflict %+v", conflict)
+	}
+
+	deleted := result.Conflicts[1]
+	if deleted.Variable != "EDIT_DELETE" || deleted.Ours != nil || *deleted.Theirs != "2" {
+		t.Errorf("expected EDIT_DELETE to conflict with a deletion, got %+v", deleted)
+	}
+}
+
+func TestMergeDocuments(t *testing.T) {
+	parse := func(content string) *env.Document {
+		doc, err := env.ParseDocument(strings.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+
+	base := parse("# Server\nPORT=3000\nHOST=localhost\nOLD=1\nTOKEN=a\n")
+	ours := parse("# Server\nPORT=4000 # changed\nHOST=localhost\nOLD=1\nTOKEN=b\n")
+	theirs := parse("# Server\nPORT=3000\nHOST='db'\nTOKEN=c\n\n# Added\nexport NEW=\"x y\" # new\n")
+
+	merged, result := env.MergeDocuments(base, ours, theirs)
+
+	expected := `# Server
+PORT=4000 # changed
+HOST=db
+<<<<<<< ours
+TOKEN=b
+||||||| base
+TOKEN=a
+=======
+TOKEN=c
+>>>>>>> theirs
+export NEW="x y" # new
+`
+
+	if got := merged.String(); got != expected {
+		t.Errorf("merged document =\n%s\nwant\n%s", got, expected)
+	}
+
+	if len(result.Conflicts) != 1 {
+		t.Errorf("expected 1 conflict, got %v", result.Conflicts)
+	}
+
+	if merged.Err() == nil {
+		t.Error("expected conflict markers to make the document invalid")
+	}
+
+	if got := ours.String(); got != "# Server\nPORT=4000 # changed\nHOST=localhost\nOLD=1\nTOKEN=b\n" {
+		t.Errorf("ours was modified: %q", got)
+	}
+}
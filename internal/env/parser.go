@@ -2,36 +2,81 @@ package env
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"sort"
 	"strings"
-
-	"github.com/joho/godotenv"
 )
 
 type Vars map[string]string
 
 func ParseFile(filename string) (Vars, error) {
-	if filename == "" {
-		return nil, fmt.Errorf("filename cannot be empty")
+	raw, err := ParseFileRaw(filename)
+
+	if err != nil {
+		return nil, err
 	}
 
-	info, err := os.Stat(filename)
+	vars, err := Expand(raw, ExpandOptions{})
 
-	if os.IsNotExist(err) {
-		return nil, fmt.Errorf("file does not exist: %s", filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand env file %s: %w", filename, err)
 	}
-	if info.IsDir() {
-		return nil, fmt.Errorf("expected a file but got a directory: %s", filename)
+
+	return vars, nil
+}
+
+// ParseFileRaw reads filename without resolving references. The values are in
+// the syntax understood by Expand, so raw values from several files can be
+// merged and then expanded together.
+func ParseFileRaw(filename string) (Vars, error) {
+	if err := checkFile(filename); err != nil {
+		return nil, err
 	}
 
-	vars, err := godotenv.Read(filename)
+	doc, err := ParseDocumentFile(filename)
 
 	if err != nil {
+		return nil, err
+	}
+
+	if err := doc.Err(); err != nil {
 		return nil, fmt.Errorf("failed to read env file %s: %w", filename, err)
 	}
 
-	return Vars(vars), nil
+	return doc.Templates(), nil
+}
+
+// checkFile reports a missing file or a directory with a clearer message than
+// opening it would give.
+func checkFile(filename string) error {
+	if filename == "" {
+		return fmt.Errorf("filename cannot be empty")
+	}
+
+	info, err := os.Stat(filename)
+
+	switch {
+	case os.IsNotExist(err):
+		return fmt.Errorf("file does not exist: %s", filename)
+	case err != nil:
+		return fmt.Errorf("failed to read env file %s: %w", filename, err)
+	case info.IsDir():
+		return fmt.Errorf("expected a file but got a directory: %s", filename)
+	}
+
+	return nil
+}
+
+// Merge combines vars in order into a new Vars, so later values win.
+func Merge(vars ...Vars) Vars {
+	merged := make(Vars)
+
+	for _, v := range vars {
+		maps.Copy(merged, v)
+	}
+
+	return merged
 }
 
 func (e Vars) Keys() []string {
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/tommyalmeida/envsync/internal/env"
@@ -116,3 +117,103 @@ func TestEnvVars_WriteToFile(t *testing.T) {
 		t.Errorf("expected %+v, got %+v", envVars, result)
 	}
 }
+
+func TestMerge(t *testing.T) {
+	base := env.Vars{"A": "1", "B": "2"}
+	override := env.Vars{"B": "3", "C": "4"}
+
+	merged := env.Merge(base, override)
+
+	expected := env.Vars{"A": "1", "B": "3", "C": "4"}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("expected %+v, got %+v", expected, merged)
+	}
+
+	if base["B"] != "2" {
+		t.Error("expected Merge to leave its arguments untouched")
+	}
+}
+
+func TestParseFile_Expansion(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), ".env")
+	content := `HOST=localhost
+URL="http://${HOST}:${PORT:-3000}"
+LITERAL='${HOST}'
+ESCAPED="\${HOST}"
+UNQUOTED=\$HOST
+`
+
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	result, err := env.ParseFile(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := env.Vars{
+		"HOST":     "localhost",
+		"URL":      "http://localhost:3000",
+		"LITERAL":  "${HOST}",
+		"ESCAPED":  "${HOST}",
+		"UNQUOTED": "$HOST",
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+
+	raw, err := env.ParseFileRaw(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if raw["URL"] != "http://${HOST}:${PORT:-3000}" || raw["LITERAL"] != "$${HOST}" {
+		t.Errorf("unexpected raw values %+v", raw)
+	}
+}
+
+func TestParseFile_InvalidLines(t *testing.T) {
+	for _, content := range []string{"VALID=1\nnot an assignment\n", "QUOTED=\"unterminated\n"} {
+		filename := filepath.Join(t.TempDir(), ".env")
+
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+
+		if _, err := env.ParseFile(filename); err == nil {
+			t.Errorf("expected error for %q, got none", content)
+		}
+	}
+}
+
+func TestParseFileRaw_Errors(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.env")
+
+	if err := os.WriteFile(invalid, []byte("not an assignment\n"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{filepath.Join(dir, "missing.env"), "file does not exist: " + filepath.Join(dir, "missing.env")},
+		{dir, "expected a file but got a directory: " + dir},
+		{invalid, "failed to read env file " + invalid + ": line 1: invalid assignment"},
+	}
+
+	for _, tt := range tests {
+		_, err := env.ParseFileRaw(tt.filename)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("ParseFileRaw(%s) error = %v, want %q", tt.filename, err, tt.want)
+			continue
+		}
+
+		if n := strings.Count(err.Error(), tt.filename); n != 1 {
+			t.Errorf("ParseFileRaw(%s) error %q names the file %d times", tt.filename, err, n)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package env
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/tommyalmeida/envsync/internal/config"
+	"github.com/tommyalmeida/envsync/pkg/schema"
+)
+
+const (
+	RuleRequireAll     = "require_all"
+	RuleAllowExtra     = "allow_extra"
+	RuleIgnorePatterns = "ignore_patterns"
+)
+
+// RuleMatch records that a rule from the config's rules block changed how a
+// variable was treated.
+type RuleMatch struct {
+	Variable string `json:"variable"`
+	Rule     string `json:"rule"`
+	Pattern  string `json:"pattern,omitempty"`
+}
+
+// RuleSet is the compiled form of config.Rules shared by the validator, the
+// differ and the syncer so every command applies the rules the same way.
+type RuleSet struct {
+	RequireAll bool
+	AllowExtra bool
+
+	patterns []*regexp.Regexp
+	schema   map[string]schema.Variable
+}
+
+// DefaultRuleSet allows extras and ignores nothing, matching the behavior of
+// a config without a rules block.
+func DefaultRuleSet() *RuleSet {
+	return &RuleSet{AllowExtra: true}
+}
+
+func NewRuleSet(rules config.Rules) (*RuleSet, error) {
+	rs := &RuleSet{
+		RequireAll: rules.RequireAll,
+		AllowExtra: rules.AllowExtra,
+	}
+
+	for _, pattern := range rules.IgnorePatterns {
+		re, err := regexp.Compile(pattern)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore pattern %q: %w", pattern, err)
+		}
+
+		rs.patterns = append(rs.patterns, re)
+	}
+
+	return rs, nil
+}
+
+// WithSchema sets the schema that require_all and allow_extra refer to when
+// comparing env files, which unlike validation and sync have none of their
+// own. Without it a comparison only applies the ignore patterns.
+func (rs *RuleSet) WithSchema(s schema.Schema) *RuleSet {
+	rs.schema = s.Variables
+	return rs
+}
+
+// Ignored reports whether key matches one of the ignore patterns and, if so,
+// which pattern matched.
+func (rs *RuleSet) Ignored(key string) (string, bool) {
+	if rs == nil {
+		return "", false
+	}
+
+	for _, re := range rs.patterns {
+		if re.MatchString(key) {
+			return re.String(), true
+		}
+	}
+
+	return "", false
+}
+
+// Filter returns a copy of vars without ignored keys, along with a match for
+// every key that was dropped.
+func (rs *RuleSet) Filter(vars Vars) (Vars, []RuleMatch) {
+	if rs == nil || len(rs.patterns) == 0 {
+		return vars, nil
+	}
+
+	filtered := make(Vars, len(vars))
+	var matches []RuleMatch
+
+	for _, key := range vars.Keys() {
+		if pattern, ignored := rs.Ignored(key); ignored {
+			matches = append(matches, RuleMatch{Variable: key, Rule: RuleIgnorePatterns, Pattern: pattern})
+			continue
+		}
+
+		filtered[key] = vars[key]
+	}
+
+	return filtered, matches
+}
@@ -0,0 +1,87 @@
+package env
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/tommyalmeida/envsync/internal/config"
+)
+
+// Masker replaces secret values with a short salted hash, so output can show
+// whether two values match without revealing them.
+type Masker struct {
+	isSecret func(key string) bool
+	salt     []byte
+}
+
+// NewMasker masks the variables cfg considers secret. Without a configured
+// salt a random one is used, so hashes only compare within a single run.
+func NewMasker(cfg *config.Config) (*Masker, error) {
+	salt := []byte(cfg.Secrets.Salt)
+
+	if len(salt) == 0 {
+		salt = make([]byte, 16)
+
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Masker{isSecret: cfg.IsSecret, salt: salt}, nil
+}
+
+// Mask returns value, or its masked form when key is secret. Empty values are
+// returned as is since there is nothing to hide.
+func (m *Masker) Mask(key, value string) string {
+	if m == nil || value == "" || !m.isSecret(key) {
+		return value
+	}
+
+	mac := hmac.New(sha256.New, m.salt)
+	mac.Write([]byte(value))
+
+	return "<secret:" + hex.EncodeToString(mac.Sum(nil))[:8] + ">"
+}
+
+func (m *Masker) MaskDiff(diff DiffResult) DiffResult {
+	different := make(map[string]Diff, len(diff.Different))
+
+	for key, values := range diff.Different {
+		different[key] = Diff{
+			Source: m.Mask(key, values.Source),
+			Target: m.Mask(key, values.Target),
+		}
+	}
+
+	diff.Different = different
+
+	return diff
+}
+
+func (m *Masker) MaskMerge(result MergeResult) MergeResult {
+	mask := func(key string, value *string) *string {
+		if value == nil {
+			return nil
+		}
+
+		masked := m.Mask(key, *value)
+		return &masked
+	}
+
+	conflicts := make([]MergeConflict, len(result.Conflicts))
+
+	for i, conflict := range result.Conflicts {
+		conflicts[i] = MergeConflict{
+			Variable: conflict.Variable,
+			Base:     mask(conflict.Variable, conflict.Base),
+			Ours:     mask(conflict.Variable, conflict.Ours),
+			Theirs:   mask(conflict.Variable, conflict.Theirs),
+		}
+	}
+
+	result.Conflicts = conflicts
+
+	return result
+}
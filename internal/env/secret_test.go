@@ -0,0 +1,60 @@
+package env_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tommyalmeida/envsync/internal/config"
+	"github.com/tommyalmeida/envsync/internal/env"
+	"github.com/tommyalmeida/envsync/pkg/schema"
+)
+
+func TestMasker_MaskDiff(t *testing.T) {
+	cfg := &config.Config{
+		Schema: schema.Schema{Variables: map[string]schema.Variable{
+			"DATABASE_URL": {Secret: true},
+		}},
+		Secrets: config.Secrets{Patterns: config.DefaultSecretPatterns, Salt: "test"},
+	}
+
+	masker, err := env.NewMasker(cfg)
+	if err != nil {
+		t.Fatalf("NewMasker() error = %v", err)
+	}
+
+	source := env.Vars{"DATABASE_URL": "postgres://a", "API_TOKEN": "same", "PORT": "3000", "EMPTY_KEY": ""}
+	target := env.Vars{"DATABASE_URL": "postgres://b", "API_TOKEN": "other", "PORT": "8080", "EMPTY_KEY": "set"}
+
+	diff := masker.MaskDiff(env.CompareEnvs(source, target))
+
+	for _, key := range []string{"DATABASE_URL", "API_TOKEN"} {
+		values := diff.Different[key]
+
+		for _, value := range []string{values.Source, values.Target} {
+			if !strings.HasPrefix(value, "<secret:") {
+				t.Errorf("%s: value %q is not masked", key, value)
+			}
+		}
+
+		if values.Source == values.Target {
+			t.Errorf("%s: different values have the same hash %q", key, values.Source)
+		}
+	}
+
+	if got := diff.Different["PORT"]; got.Source != "3000" || got.Target != "8080" {
+		t.Errorf("PORT = %+v, want unmasked values", got)
+	}
+
+	if got := diff.Different["EMPTY_KEY"].Source; got != "" {
+		t.Errorf("EMPTY_KEY source = %q, want empty values unmasked", got)
+	}
+
+	if got, want := masker.Mask("API_TOKEN", "same"), masker.Mask("API_TOKEN", "same"); got != want {
+		t.Errorf("equal values hash differently: %q != %q", got, want)
+	}
+
+	other, _ := env.NewMasker(&config.Config{Secrets: config.Secrets{Patterns: config.DefaultSecretPatterns, Salt: "other"}})
+	if masker.Mask("API_TOKEN", "same") == other.Mask("API_TOKEN", "same") {
+		t.Error("hashes do not depend on the salt")
+	}
+}
@@ -2,53 +2,366 @@ package env
 
 import (
 	"fmt"
-
 	"maps"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/tommyalmeida/envsync/internal/config"
 )
 
 type SyncResult struct {
-	Added    []string `json:"added"`
-	Skipped  []string `json:"skipped"`
-	FilePath string   `json:"file_path"`
+	Added     []string       `json:"added"`
+	Updated   []string       `json:"updated"`
+	Removed   []string       `json:"removed"`
+	Skipped   []string       `json:"skipped"`
+	Decisions []SyncDecision `json:"decisions"`
+	FilePath  string         `json:"file_path"`
+	Rules     []RuleMatch    `json:"rules,omitempty"`
+}
+
+const (
+	ActionAdded   = "added"
+	ActionUpdated = "updated"
+	ActionRemoved = "removed"
+	ActionSkipped = "skipped"
+)
+
+// SyncDecision records what a sync did with a variable and why.
+type SyncDecision struct {
+	Variable string `json:"variable"`
+	Action   string `json:"action"`
+	Reason   string `json:"reason"`
+}
+
+// Changed reports whether the sync adds, updates or removes anything.
+func (r SyncResult) Changed() bool {
+	return len(r.Added) > 0 || len(r.Updated) > 0 || len(r.Removed) > 0
+}
+
+func (r *SyncResult) record(key, action, reason string) {
+	switch action {
+	case ActionAdded:
+		r.Added = append(r.Added, key)
+	case ActionUpdated:
+		r.Updated = append(r.Updated, key)
+	case ActionRemoved:
+		r.Removed = append(r.Removed, key)
+	case ActionSkipped:
+		r.Skipped = append(r.Skipped, key)
+	}
+
+	r.Decisions = append(r.Decisions, SyncDecision{Variable: key, Action: action, Reason: reason})
+}
+
+// Strategy selects which differences a sync resolves.
+type Strategy string
+
+const (
+	// StrategyAddOnly adds variables missing from the target.
+	StrategyAddOnly Strategy = "add-only"
+	// StrategyOverwrite also copies source values that differ.
+	StrategyOverwrite Strategy = "overwrite"
+	// StrategyPrune also removes target variables that are neither in the
+	// source nor in the schema.
+	StrategyPrune Strategy = "prune"
+	// StrategyMirror adds, overwrites and prunes.
+	StrategyMirror Strategy = "mirror"
+	// StrategyInteractive adds missing variables and asks the resolver about
+	// every value that differs.
+	StrategyInteractive Strategy = "interactive"
+)
+
+var Strategies = []Strategy{StrategyAddOnly, StrategyOverwrite, StrategyPrune, StrategyMirror, StrategyInteractive}
+
+func ParseStrategy(name string) (Strategy, error) {
+	for _, strategy := range Strategies {
+		if string(strategy) == name {
+			return strategy, nil
+		}
+	}
+
+	names := make([]string, len(Strategies))
+	for i, strategy := range Strategies {
+		names[i] = string(strategy)
+	}
+
+	return "", fmt.Errorf("unknown sync strategy %q, expected one of: %s", name, strings.Join(names, ", "))
+}
+
+func (s Strategy) overwrites() bool {
+	return s == StrategyOverwrite || s == StrategyMirror
+}
+
+func (s Strategy) prunes() bool {
+	return s == StrategyPrune || s == StrategyMirror
+}
+
+// Resolver decides whether the source value of key should replace the
+// target value during an interactive sync.
+type Resolver func(key, sourceValue, targetValue string) (bool, error)
+
+// Destination is where a sync writes the variables it changes.
+type Destination interface {
+	// Location names the destination in sync results.
+	Location() string
+	// Write stores the values of keys from vars.
+	Write(vars Vars, keys []string) error
+	// Remove deletes keys.
+	Remove(keys []string) error
+}
+
+// TemplateDestination is a Destination that can store references, such as an
+// env file. Values copied from the source are written to it as the source
+// wrote them, so a reference is not replaced by what it resolves to.
+type TemplateDestination interface {
+	Destination
+	// WriteTemplates stores the values of keys from templates, which are in
+	// the syntax understood by Expand.
+	WriteTemplates(templates Vars, keys []string) error
 }
 
 type Syncer struct {
-	config *config.Config
+	config    *config.Config
+	rules     *RuleSet
+	strategy  Strategy
+	resolve   Resolver
+	templates Vars
 }
 
 func NewSyncer(cfg *config.Config) *Syncer {
-	return &Syncer{config: cfg}
+	return &Syncer{config: cfg, rules: DefaultRuleSet(), strategy: StrategyAddOnly}
+}
+
+// WithRules makes the syncer enforce rs instead of the default rules.
+func (s *Syncer) WithRules(rs *RuleSet) *Syncer {
+	if rs == nil {
+		rs = DefaultRuleSet()
+	}
+
+	s.rules = rs
+	return s
+}
+
+// WithStrategy sets the sync strategy. resolve is required for
+// StrategyInteractive and ignored otherwise.
+func (s *Syncer) WithStrategy(strategy Strategy, resolve Resolver) *Syncer {
+	s.strategy = strategy
+	s.resolve = resolve
+	return s
+}
+
+// WithTemplates sets the source values in the syntax understood by Expand,
+// such as those returned by ParseFileRaw. Source and target are still compared
+// on their expanded values, but the values copied to a TemplateDestination
+// are taken from templates.
+func (s *Syncer) WithTemplates(templates Vars) *Syncer {
+	s.templates = templates
+	return s
 }
 
 func (s *Syncer) Sync(source, target Vars, targetFile string, dryRun bool) (SyncResult, error) {
+	return s.SyncTo(source, target, FileDestination(targetFile), dryRun)
+}
+
+func (s *Syncer) SyncTo(source, target Vars, dest Destination, dryRun bool) (SyncResult, error) {
 	result := SyncResult{
-		FilePath: targetFile,
+		FilePath: dest.Location(),
+	}
+
+	if s.strategy == StrategyInteractive && s.resolve == nil {
+		return result, fmt.Errorf("the interactive strategy needs a resolver")
 	}
 
-	diff := CompareEnvs(source, target)
+	rules := s.rules
+	diff := CompareEnvsWithRules(source, target, rules)
+
+	for _, key := range diff.Ignored {
+		result.record(key, ActionSkipped, "matches an ignore pattern")
+	}
+	result.Rules = append(result.Rules, diff.Rules...)
 
 	newTarget := make(Vars)
 	maps.Copy(newTarget, target)
 
+	// fromSource records the keys whose new value is the source value rather
+	// than a default.
+	fromSource := make(map[string]bool)
+
 	for _, key := range diff.Missing {
+		if _, inSchema := s.config.Schema.Variables[key]; !inSchema && !rules.AllowExtra {
+			result.record(key, ActionSkipped, "not in the schema and extra variables are not allowed")
+			result.Rules = append(result.Rules, RuleMatch{Variable: key, Rule: RuleAllowExtra})
+			continue
+		}
+
 		sourceValue := source[key]
 		defaultValue := s.getDefaultValue(key, sourceValue)
 
 		newTarget[key] = defaultValue
-		result.Added = append(result.Added, key)
+		fromSource[key] = defaultValue == sourceValue
+		result.record(key, ActionAdded, "missing in target")
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(diff.Different)) {
+		values := diff.Different[key]
+
+		switch {
+		case s.strategy.overwrites():
+			newTarget[key] = values.Source
+			fromSource[key] = true
+			result.record(key, ActionUpdated, "value differs from source")
+		case s.strategy == StrategyInteractive:
+			overwrite, err := s.resolve(key, values.Source, values.Target)
+
+			if err != nil {
+				return result, err
+			}
+
+			if overwrite {
+				newTarget[key] = values.Source
+				fromSource[key] = true
+				result.record(key, ActionUpdated, "source value chosen interactively")
+			} else {
+				result.record(key, ActionSkipped, "target value kept interactively")
+			}
+		default:
+			result.record(key, ActionSkipped, fmt.Sprintf("value differs, %s keeps the target value", s.strategy))
+		}
+	}
+
+	if s.strategy.prunes() {
+		for _, key := range diff.Extra {
+			if _, inSchema := s.config.Schema.Variables[key]; inSchema {
+				result.record(key, ActionSkipped, "not in source but defined in the schema")
+				continue
+			}
+
+			delete(newTarget, key)
+			result.record(key, ActionRemoved, "not in source or schema")
+		}
+	}
+
+	if rules.RequireAll {
+		for _, key := range s.schemaKeys() {
+			if _, exists := newTarget[key]; exists {
+				continue
+			}
+
+			if _, ignored := rules.Ignored(key); ignored {
+				continue
+			}
+
+			result.Rules = append(result.Rules, RuleMatch{Variable: key, Rule: RuleRequireAll})
+
+			defaultValue := s.getDefaultValue(key, "")
+			if defaultValue == "" {
+				result.record(key, ActionSkipped, "required by the schema but has no default")
+				continue
+			}
+
+			newTarget[key] = defaultValue
+			result.record(key, ActionAdded, "required by the schema, default used")
+		}
+	}
+
+	if dryRun {
+		return result, nil
 	}
 
-	if !dryRun && len(result.Added) > 0 {
-		if err := newTarget.WriteToFile(targetFile); err != nil {
-			return result, fmt.Errorf("failed to write target file: %w", err)
+	if written := append(append([]string{}, result.Added...), result.Updated...); len(written) > 0 {
+		if err := s.write(dest, newTarget, written, fromSource); err != nil {
+			return result, fmt.Errorf("failed to write target: %w", err)
+		}
+	}
+
+	if len(result.Removed) > 0 {
+		if err := dest.Remove(result.Removed); err != nil {
+			return result, fmt.Errorf("failed to remove from target: %w", err)
 		}
 	}
 
 	return result, nil
 }
 
+// write stores the values of keys from vars in dest, taking the values copied
+// from the source from the templates when dest can store them.
+func (s *Syncer) write(dest Destination, vars Vars, keys []string, fromSource map[string]bool) error {
+	templateDest, ok := dest.(TemplateDestination)
+
+	if !ok || s.templates == nil {
+		return dest.Write(vars, keys)
+	}
+
+	templates := make(Vars, len(keys))
+
+	for _, key := range keys {
+		template, exists := s.templates[key]
+
+		if !exists || !fromSource[key] {
+			template = EscapeTemplate(vars[key])
+		}
+
+		templates[key] = template
+	}
+
+	return templateDest.WriteTemplates(templates, keys)
+}
+
+// FileDestination writes to an env file through a Document so that the rest of
+// the file is left byte-for-byte as it was.
+func FileDestination(filename string) Destination {
+	return fileDestination(filename)
+}
+
+type fileDestination string
+
+func (f fileDestination) Location() string {
+	return string(f)
+}
+
+func (f fileDestination) Write(vars Vars, keys []string) error {
+	return f.WriteTemplates(EscapeTemplates(vars), keys)
+}
+
+func (f fileDestination) WriteTemplates(templates Vars, keys []string) error {
+	filename := string(f)
+	doc := NewDocument()
+
+	if _, err := os.Stat(filename); err == nil {
+		doc, err = ParseDocumentFile(filename)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, key := range keys {
+		doc.SetTemplate(key, templates[key])
+	}
+
+	return doc.WriteToFile(filename)
+}
+
+func (f fileDestination) Remove(keys []string) error {
+	filename := string(f)
+	doc, err := ParseDocumentFile(filename)
+
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		doc.Delete(key)
+	}
+
+	return doc.WriteToFile(filename)
+}
+
+func (s *Syncer) schemaKeys() []string {
+	return slices.Sorted(maps.Keys(s.config.Schema.Variables))
+}
+
 func (s *Syncer) getDefaultValue(key, originalValue string) string {
 	if defaultVal, exists := s.config.Defaults[key]; exists {
 		return defaultVal
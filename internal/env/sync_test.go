@@ -1,6 +1,9 @@
 package env_test
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/tommyalmeida/envsync/internal/config"
@@ -77,3 +80,231 @@ func TestSyncer_Sync(t *testing.T) {
 		})
 	}
 }
+
+func TestSyncer_SyncRules(t *testing.T) {
+	cfg := &config.Config{
+		Schema: schema.Schema{
+			Variables: map[string]schema.Variable{
+				"PORT":     {Default: "3000"},
+				"HOST":     {},
+				"LOG_FILE": {},
+			},
+		},
+	}
+
+	rs, err := env.NewRuleSet(config.Rules{
+		RequireAll:     true,
+		AllowExtra:     false,
+		IgnorePatterns: []string{"^TEMP_"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	source := env.Vars{
+		"LOG_FILE": "/var/log/app.log",
+		"TEMP_DIR": "/tmp",
+		"EXTRA":    "value",
+	}
+
+	result, err := env.NewSyncer(cfg).WithRules(rs).Sync(source, env.Vars{}, t.TempDir()+"/target.env", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(result.Added, []string{"LOG_FILE", "PORT"}) {
+		t.Errorf("expected LOG_FILE and PORT to be added, got %v", result.Added)
+	}
+
+	if !reflect.DeepEqual(result.Skipped, []string{"TEMP_DIR", "EXTRA", "HOST"}) {
+		t.Errorf("expected TEMP_DIR, EXTRA and HOST to be skipped, got %v", result.Skipped)
+	}
+
+	rules := make(map[string]string)
+	for _, match := range result.Rules {
+		rules[match.Variable] = match.Rule
+	}
+
+	expected := map[string]string{
+		"TEMP_DIR": env.RuleIgnorePatterns,
+		"EXTRA":    env.RuleAllowExtra,
+		"PORT":     env.RuleRequireAll,
+		"HOST":     env.RuleRequireAll,
+	}
+
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("expected rules %v, got %v", expected, rules)
+	}
+}
+
+func TestNewRuleSet_InvalidIgnorePattern(t *testing.T) {
+	_, err := env.NewRuleSet(config.Rules{IgnorePatterns: []string{"("}})
+	if err == nil {
+		t.Error("expected error for invalid ignore pattern, got none")
+	}
+}
+
+func TestSyncer_SyncStrategies(t *testing.T) {
+	cfg := &config.Config{
+		Schema: schema.Schema{
+			Variables: map[string]schema.Variable{
+				"SCHEMA_ONLY": {},
+			},
+		},
+	}
+
+	rs, err := env.NewRuleSet(config.Rules{AllowExtra: true, IgnorePatterns: []string{"^LOCAL_"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	source := env.Vars{"NEW": "1", "CHANGED": "source", "SAME": "x"}
+	target := env.Vars{"CHANGED": "target", "SAME": "x", "STALE": "old", "SCHEMA_ONLY": "kept", "LOCAL_DIR": "/tmp"}
+
+	tests := []struct {
+		strategy env.Strategy
+		resolve  env.Resolver
+		updated  []string
+		removed  []string
+		skipped  []string
+		content  string
+	}{
+		{
+			strategy: env.StrategyAddOnly,
+			skipped:  []string{"LOCAL_DIR", "CHANGED"},
+			content:  "CHANGED=target\nSTALE=old\nNEW=1\n",
+		},
+		{
+			strategy: env.StrategyOverwrite,
+			updated:  []string{"CHANGED"},
+			skipped:  []string{"LOCAL_DIR"},
+			content:  "CHANGED=source\nSTALE=old\nNEW=1\n",
+		},
+		{
+			strategy: env.StrategyPrune,
+			removed:  []string{"STALE"},
+			skipped:  []string{"LOCAL_DIR", "CHANGED", "SCHEMA_ONLY"},
+			content:  "CHANGED=target\nNEW=1\n",
+		},
+		{
+			strategy: env.StrategyMirror,
+			updated:  []string{"CHANGED"},
+			removed:  []string{"STALE"},
+			skipped:  []string{"LOCAL_DIR", "SCHEMA_ONLY"},
+			content:  "CHANGED=source\nNEW=1\n",
+		},
+		{
+			strategy: env.StrategyInteractive,
+			resolve: func(key, sourceValue, targetValue string) (bool, error) {
+				return key == "CHANGED" && sourceValue == "source" && targetValue == "target", nil
+			},
+			updated: []string{"CHANGED"},
+			skipped: []string{"LOCAL_DIR"},
+			content: "CHANGED=source\nSTALE=old\nNEW=1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			targetFile := filepath.Join(t.TempDir(), "target.env")
+			if err := os.WriteFile(targetFile, []byte("CHANGED=target\nSTALE=old\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			syncer := env.NewSyncer(cfg).WithRules(rs).WithStrategy(tt.strategy, tt.resolve)
+
+			result, err := syncer.Sync(source, target, targetFile, false)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(result.Added, []string{"NEW"}) {
+				t.Errorf("added = %v, want [NEW]", result.Added)
+			}
+
+			if !reflect.DeepEqual(result.Updated, tt.updated) {
+				t.Errorf("updated = %v, want %v", result.Updated, tt.updated)
+			}
+
+			if !reflect.DeepEqual(result.Removed, tt.removed) {
+				t.Errorf("removed = %v, want %v", result.Removed, tt.removed)
+			}
+
+			if !reflect.DeepEqual(result.Skipped, tt.skipped) {
+				t.Errorf("skipped = %v, want %v", result.Skipped, tt.skipped)
+			}
+
+			decisions := len(result.Added) + len(result.Updated) + len(result.Removed) + len(result.Skipped)
+			if len(result.Decisions) != decisions {
+				t.Errorf("got %d decisions for %d variables", len(result.Decisions), decisions)
+			}
+
+			for _, decision := range result.Decisions {
+				if decision.Reason == "" {
+					t.Errorf("decision for %s has no reason", decision.Variable)
+				}
+			}
+
+			content, err := os.ReadFile(targetFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(content) != tt.content {
+				t.Errorf("target file = %q, want %q", content, tt.content)
+			}
+		})
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	if strategy, err := env.ParseStrategy("mirror"); err != nil || strategy != env.StrategyMirror {
+		t.Errorf("ParseStrategy(mirror) = %q, %v", strategy, err)
+	}
+
+	if _, err := env.ParseStrategy("replace"); err == nil {
+		t.Error("expected error for unknown strategy, got none")
+	}
+
+	_, err := env.NewSyncer(&config.Config{}).WithStrategy(env.StrategyInteractive, nil).
+		Sync(env.Vars{}, env.Vars{}, "unused.env", true)
+	if err == nil {
+		t.Error("expected error for interactive sync without a resolver, got none")
+	}
+}
+
+func TestSyncer_SyncWithTemplates(t *testing.T) {
+	targetFile := filepath.Join(t.TempDir(), "target.env")
+	if err := os.WriteFile(targetFile, []byte("URL=\"stale\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	templates := env.Vars{
+		"HOST":  "localhost",
+		"URL":   "http://${HOST}:${PORT}",
+		"PRICE": "$$5",
+		"PORT":  "${DEFAULT_PORT}",
+	}
+	source := env.Vars{"HOST": "localhost", "URL": "http://localhost:8080", "PRICE": "$5", "PORT": "8080"}
+	cfg := &config.Config{
+		Defaults: map[string]string{"PORT": "9090"},
+	}
+
+	_, err := env.NewSyncer(cfg).
+		WithStrategy(env.StrategyOverwrite, nil).
+		WithTemplates(templates).
+		Sync(source, env.Vars{"URL": "stale"}, targetFile, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(targetFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "URL=\"http://${HOST}:${PORT}\"\nHOST=localhost\nPORT=9090\nPRICE=\"\\$5\"\n"
+	if string(content) != want {
+		t.Errorf("target file = %q, want %q", content, want)
+	}
+}
@@ -13,15 +13,36 @@ type ValidationResult struct {
 	Errors  []schema.ValidationError `json:"errors,omitempty"`
 	Missing []string                 `json:"missing,omitempty"`
 	Extra   []string                 `json:"extra,omitempty"`
+	Ignored []string                 `json:"ignored,omitempty"`
+	Rules   []RuleMatch              `json:"rules,omitempty"`
 }
 
 type Validator struct {
-	schema schema.Schema
-	debug  bool
+	schema    schema.Schema
+	rules     *RuleSet
+	positions Positions
+	debug     bool
 }
 
 func NewValidator(s schema.Schema) *Validator {
-	return &Validator{schema: s, debug: false}
+	return &Validator{schema: s, rules: DefaultRuleSet(), debug: false}
+}
+
+// WithRules makes the validator enforce rs instead of the default rules.
+func (v *Validator) WithRules(rs *RuleSet) *Validator {
+	if rs == nil {
+		rs = DefaultRuleSet()
+	}
+
+	v.rules = rs
+	return v
+}
+
+// WithPositions makes the validator attach the position of the variable to
+// every error.
+func (v *Validator) WithPositions(positions Positions) *Validator {
+	v.positions = positions
+	return v
 }
 
 func (v *Validator) Validate(envVars Vars) ValidationResult {
@@ -30,39 +51,115 @@ func (v *Validator) Validate(envVars Vars) ValidationResult {
 		Errors: []schema.ValidationError{},
 	}
 
+	envVars, ignored := v.rules.Filter(envVars)
+	for _, match := range ignored {
+		result.Ignored = append(result.Ignored, match.Variable)
+		result.Rules = append(result.Rules, match)
+	}
+
 	if v.debug {
 		log.Printf("DEBUG: Schema variables: %v\n", v.getSchemaKeys())
 		log.Printf("DEBUG: Env variables: %v\n", envVars.Keys())
 	}
 
 	for name, variable := range v.schema.Variables {
+		if _, ignored := v.rules.Ignored(name); ignored {
+			continue
+		}
+
+		forced := v.rules.RequireAll && !variable.Required
+		if forced {
+			variable.Required = true
+		}
+
 		value, exists := envVars[name]
 		if !exists {
 			if variable.Required {
 				result.Missing = append(result.Missing, name)
 				result.Valid = false
+
+				if forced {
+					result.Rules = append(result.Rules, RuleMatch{Variable: name, Rule: RuleRequireAll})
+				}
 			}
 			continue
 		}
 
+		if forced && value == "" {
+			result.Errors = append(result.Errors, schema.ValidationError{
+				Variable: name,
+				Message:  "required variable is empty",
+			})
+			result.Rules = append(result.Rules, RuleMatch{Variable: name, Rule: RuleRequireAll})
+			result.Valid = false
+			continue
+		}
+
 		if errors := v.schema.ValidateVariable(name, value); len(errors) > 0 {
 			result.Errors = append(result.Errors, errors...)
 			result.Valid = false
 		}
 	}
 
+	if errors := v.schema.ValidateConstraints(envVars); len(errors) > 0 {
+		result.Errors = append(result.Errors, errors...)
+		result.Valid = false
+	}
+
+	if errors := v.schema.ValidateRules(envVars); len(errors) > 0 {
+		result.Errors = append(result.Errors, errors...)
+		result.Valid = false
+	}
+
 	for name := range envVars {
 		if _, exists := v.schema.Variables[name]; !exists {
 			result.Extra = append(result.Extra, name)
+
+			if !v.rules.AllowExtra {
+				result.Rules = append(result.Rules, RuleMatch{Variable: name, Rule: RuleAllowExtra})
+				result.Valid = false
+			}
+		}
+	}
+
+	for i, err := range result.Errors {
+		if position, ok := v.positions[err.Variable]; ok && err.Position == nil {
+			result.Errors[i].Position = &position
 		}
 	}
 
 	sort.Strings(result.Missing)
 	sort.Strings(result.Extra)
+	sort.SliceStable(result.Errors, func(i, j int) bool {
+		return result.Errors[i].Variable < result.Errors[j].Variable
+	})
+	sort.SliceStable(result.Rules, func(i, j int) bool {
+		return result.Rules[i].Variable < result.Rules[j].Variable
+	})
 
 	return result
 }
 
+// ApplyDefaults returns a copy of envVars where every optional schema variable
+// that is not set gets its schema default. It also returns the keys it filled.
+func (v *Validator) ApplyDefaults(envVars Vars) (Vars, []string) {
+	result := Merge(envVars)
+
+	var applied []string
+	for name, variable := range v.schema.Variables {
+		if _, exists := envVars[name]; exists || variable.Required || variable.Default == "" {
+			continue
+		}
+
+		result[name] = variable.Default
+		applied = append(applied, name)
+	}
+
+	sort.Strings(applied)
+
+	return result, applied
+}
+
 func (v *Validator) getSchemaKeys() []string {
 	keys := make([]string, 0, len(v.schema.Variables))
 
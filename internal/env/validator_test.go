@@ -1,8 +1,11 @@
 package env_test
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/tommyalmeida/envsync/internal/config"
 	"github.com/tommyalmeida/envsync/internal/env"
 	"github.com/tommyalmeida/envsync/pkg/schema"
 )
@@ -101,3 +104,180 @@ func TestValidator_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestValidator_ValidateRules(t *testing.T) {
+	testSchema := schema.Schema{
+		Variables: map[string]schema.Variable{
+			"REQUIRED_VAR": {Required: true},
+			"OPTIONAL_VAR": {Required: false},
+			"TEMP_VAR":     {Required: true},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		rules         config.Rules
+		envVars       env.Vars
+		expectValid   bool
+		expectMissing []string
+		expectIgnored []string
+		expectRules   []env.RuleMatch
+	}{
+		{
+			name:          "require_all treats optional variables as required",
+			rules:         config.Rules{RequireAll: true, AllowExtra: true},
+			envVars:       env.Vars{"REQUIRED_VAR": "value", "TEMP_VAR": "value"},
+			expectValid:   false,
+			expectMissing: []string{"OPTIONAL_VAR"},
+			expectRules:   []env.RuleMatch{{Variable: "OPTIONAL_VAR", Rule: env.RuleRequireAll}},
+		},
+		{
+			name:        "allow_extra false fails on extras",
+			rules:       config.Rules{AllowExtra: false},
+			envVars:     env.Vars{"REQUIRED_VAR": "value", "TEMP_VAR": "value", "EXTRA_VAR": "extra"},
+			expectValid: false,
+			expectRules: []env.RuleMatch{{Variable: "EXTRA_VAR", Rule: env.RuleAllowExtra}},
+		},
+		{
+			name:          "ignored variables are dropped",
+			rules:         config.Rules{AllowExtra: false, IgnorePatterns: []string{"^TEMP_"}},
+			envVars:       env.Vars{"REQUIRED_VAR": "value", "TEMP_EXTRA": "value"},
+			expectValid:   true,
+			expectIgnored: []string{"TEMP_EXTRA"},
+			expectRules:   []env.RuleMatch{{Variable: "TEMP_EXTRA", Rule: env.RuleIgnorePatterns, Pattern: "^TEMP_"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := env.NewRuleSet(tt.rules)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			result := env.NewValidator(testSchema).WithRules(rules).Validate(tt.envVars)
+
+			if result.Valid != tt.expectValid {
+				t.Errorf("expected Valid=%v, got Valid=%v", tt.expectValid, result.Valid)
+			}
+
+			if !reflect.DeepEqual(result.Missing, tt.expectMissing) {
+				t.Errorf("expected Missing=%v, got Missing=%v", tt.expectMissing, result.Missing)
+			}
+
+			if !reflect.DeepEqual(result.Ignored, tt.expectIgnored) {
+				t.Errorf("expected Ignored=%v, got Ignored=%v", tt.expectIgnored, result.Ignored)
+			}
+
+			if !reflect.DeepEqual(result.Rules, tt.expectRules) {
+				t.Errorf("expected Rules=%v, got Rules=%v", tt.expectRules, result.Rules)
+			}
+		})
+	}
+}
+
+func TestValidator_ValidateConstraints(t *testing.T) {
+	testSchema := schema.Schema{
+		Variables: map[string]schema.Variable{
+			"DATABASE_URL": {ConflictsWith: []string{"DB_HOST"}},
+			"DB_HOST":      {},
+		},
+	}
+
+	result := env.NewValidator(testSchema).Validate(env.Vars{"DATABASE_URL": "postgres://db", "DB_HOST": "db"})
+
+	if result.Valid {
+		t.Fatal("expected validation to fail")
+	}
+
+	expected := []schema.ValidationError{{
+		Variable: "DATABASE_URL",
+		Message:  "conflicts with DB_HOST, only one of them may be set",
+	}}
+	if !reflect.DeepEqual(result.Errors, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result.Errors)
+	}
+}
+
+func TestValidator_ValidateExpressionRules(t *testing.T) {
+	testSchema := schema.Schema{
+		Variables: map[string]schema.Variable{
+			"POOL_MIN": {Type: "integer", Required: true},
+			"POOL_MAX": {Type: "integer", Required: true},
+		},
+		Rules: []schema.Rule{{Expr: "POOL_MAX >= POOL_MIN", Message: "POOL_MAX must be at least POOL_MIN"}},
+	}
+
+	validator := env.NewValidator(testSchema)
+
+	if result := validator.Validate(env.Vars{"POOL_MIN": "2", "POOL_MAX": "8"}); !result.Valid {
+		t.Errorf("expected validation to pass, got %+v", result)
+	}
+
+	result := validator.Validate(env.Vars{"POOL_MIN": "8", "POOL_MAX": "2"})
+
+	expected := []schema.ValidationError{{Variable: "POOL_MAX", Message: "POOL_MAX must be at least POOL_MIN"}}
+	if result.Valid || !reflect.DeepEqual(result.Errors, expected) {
+		t.Errorf("expected errors %+v, got %+v", expected, result.Errors)
+	}
+}
+
+func TestValidator_ValidateWithPositions(t *testing.T) {
+	testSchema := schema.Schema{
+		Variables: map[string]schema.Variable{
+			"PORT":    {Type: "port"},
+			"API_URL": {Type: "url"},
+		},
+	}
+
+	doc, err := env.ParseDocument(strings.NewReader("# server\nexport PORT=http\nAPI_URL=nope\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	doc.File = ".env"
+
+	result := env.NewValidator(testSchema).WithPositions(doc.Positions()).Validate(doc.Vars())
+
+	expected := map[string]schema.Position{
+		"API_URL": {File: ".env", Line: 3, Column: 1},
+		"PORT":    {File: ".env", Line: 2, Column: 8},
+	}
+
+	if len(result.Errors) != 2 {
+		t.Fatalf("expected 2 errors, got %+v", result.Errors)
+	}
+
+	for _, err := range result.Errors {
+		if err.Position == nil || *err.Position != expected[err.Variable] {
+			t.Errorf("expected %s at %v, got %v", err.Variable, expected[err.Variable], err.Position)
+		}
+	}
+}
+
+func TestValidator_ApplyDefaults(t *testing.T) {
+	testSchema := schema.Schema{
+		Variables: map[string]schema.Variable{
+			"PORT":      {Default: "3000"},
+			"LOG_LEVEL": {Default: "info"},
+			"HOST":      {Required: true, Default: "localhost"},
+			"TIMEOUT":   {},
+		},
+	}
+
+	envVars := env.Vars{"LOG_LEVEL": "debug"}
+	result, applied := env.NewValidator(testSchema).ApplyDefaults(envVars)
+
+	expected := env.Vars{"LOG_LEVEL": "debug", "PORT": "3000"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+
+	if !reflect.DeepEqual(applied, []string{"PORT"}) {
+		t.Errorf("expected PORT to be applied, got %v", applied)
+	}
+
+	if _, exists := envVars["PORT"]; exists {
+		t.Error("expected ApplyDefaults to leave its input untouched")
+	}
+}
@@ -0,0 +1,225 @@
+// Package format rewrites env files in canonical form, the way gofmt does for
+// Go code.
+package format
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/tommyalmeida/envsync/internal/env"
+)
+
+// entry is an assignment with the comment lines directly above it, which
+// move with it.
+type entry struct {
+	comments []string
+	line     *env.Line
+}
+
+// Format returns doc in canonical form:
+//
+//   - Variables listed in order, the schema variables in the order the config
+//     declares them, come first and keep that order.
+//   - The other variables are grouped by the part of their name before the
+//     first underscore and sorted within each group. Variables whose prefix
+//     no other variable shares form one group before the others.
+//   - Groups are separated by one blank line, and comments above a variable
+//     move with it. Comments before the first blank line of the file stay at
+//     the top.
+//   - Values are unquoted when they can be, and double-quoted otherwise.
+//   - Inline comments of consecutive lines are aligned.
+//
+// Values, and what they expand to, are never changed. Files with lines that
+// are not assignments, comments or blank cannot be formatted.
+func Format(doc *env.Document, order []string) (*env.Document, error) {
+	if err := doc.Err(); err != nil {
+		return nil, err
+	}
+
+	header, entries, trailer := split(doc)
+
+	var blocks []string
+
+	if len(header) > 0 {
+		blocks = append(blocks, strings.Join(header, "\n"))
+	}
+
+	for _, group := range groups(entries, order) {
+		blocks = append(blocks, render(group))
+	}
+
+	if len(trailer) > 0 {
+		blocks = append(blocks, strings.Join(trailer, "\n"))
+	}
+
+	text := strings.Join(blocks, "\n\n")
+	if text != "" {
+		text += "\n"
+	}
+
+	formatted, err := env.ParseDocument(strings.NewReader(text))
+
+	if err != nil {
+		return nil, err
+	}
+
+	formatted.File = doc.File
+	formatted.LineEnding = doc.LineEnding
+
+	// Guard the promise that formatting never changes a value.
+	before, after := doc.Templates(), formatted.Templates()
+	for _, key := range slices.Sorted(maps.Keys(before)) {
+		if before[key] != after[key] {
+			return nil, fmt.Errorf("formatting would change the value of %s", key)
+		}
+	}
+
+	return formatted, nil
+}
+
+// split divides doc into the comments at the top, the assignments with their
+// comments and the comments after the last assignment.
+func split(doc *env.Document) ([]string, []entry, []string) {
+	var header, pending []string
+	var entries []entry
+
+	for _, line := range doc.Lines {
+		switch line.Kind {
+		case env.LineComment:
+			pending = append(pending, strings.TrimRight(line.Comment, " \t"))
+		case env.LineBlank:
+			// Comments that end before the first blank line above the
+			// first assignment describe the file.
+			if len(entries) == 0 && len(pending) > 0 {
+				if len(header) > 0 {
+					header = append(header, "")
+				}
+
+				header = append(header, pending...)
+				pending = nil
+			}
+		case env.LineAssignment:
+			entries = append(entries, entry{comments: pending, line: line})
+			pending = nil
+		}
+	}
+
+	return header, entries, pending
+}
+
+// groups orders the entries as described on Format.
+func groups(entries []entry, order []string) [][]entry {
+	index := make(map[string]int, len(order))
+	for i, name := range order {
+		if _, exists := index[name]; !exists {
+			index[name] = i
+		}
+	}
+
+	// A repeated key counts once, so it does not make a group on its own.
+	prefixes := make(map[string]map[string]bool)
+	for _, e := range entries {
+		if _, declared := index[e.line.Key]; !declared {
+			p := prefix(e.line.Key)
+			if prefixes[p] == nil {
+				prefixes[p] = make(map[string]bool)
+			}
+			prefixes[p][e.line.Key] = true
+		}
+	}
+
+	var declared []entry
+	byPrefix := make(map[string][]entry)
+
+	for _, e := range entries {
+		if _, ok := index[e.line.Key]; ok {
+			declared = append(declared, e)
+			continue
+		}
+
+		p := prefix(e.line.Key)
+		if len(prefixes[p]) < 2 {
+			p = ""
+		}
+
+		byPrefix[p] = append(byPrefix[p], e)
+	}
+
+	// Stable sorts keep repeated keys in file order, so the last one still
+	// wins.
+	slices.SortStableFunc(declared, func(a, b entry) int {
+		return cmp.Compare(index[a.line.Key], index[b.line.Key])
+	})
+
+	var result [][]entry
+
+	if len(declared) > 0 {
+		result = append(result, declared)
+	}
+
+	for _, p := range slices.Sorted(maps.Keys(byPrefix)) {
+		group := byPrefix[p]
+
+		slices.SortStableFunc(group, func(a, b entry) int {
+			return strings.Compare(a.line.Key, b.line.Key)
+		})
+
+		result = append(result, group)
+	}
+
+	return result
+}
+
+func prefix(key string) string {
+	p, _, _ := strings.Cut(key, "_")
+	return p
+}
+
+// render writes a group, aligning the inline comments of runs of
+// consecutive assignments that have one.
+func render(group []entry) string {
+	var lines []string
+
+	for start := 0; start < len(group); {
+		end := start + 1
+
+		if group[start].line.Comment != "" {
+			for end < len(group) && group[end].line.Comment != "" && len(group[end].comments) == 0 {
+				end++
+			}
+		}
+
+		width := 0
+		for _, e := range group[start:end] {
+			width = max(width, len(assignment(e.line)))
+		}
+
+		for _, e := range group[start:end] {
+			text := assignment(e.line)
+
+			if e.line.Comment != "" {
+				text += strings.Repeat(" ", width-len(text)+1) + strings.TrimRight(e.line.Comment, " \t")
+			}
+
+			lines = append(lines, e.comments...)
+			lines = append(lines, text)
+		}
+
+		start = end
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func assignment(line *env.Line) string {
+	text := line.Key + "=" + env.QuoteTemplate(line.Template())
+
+	if line.Export {
+		text = "export " + text
+	}
+
+	return text
+}
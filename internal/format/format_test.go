@@ -0,0 +1,135 @@
+package format_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tommyalmeida/envsync/internal/env"
+	"github.com/tommyalmeida/envsync/internal/format"
+)
+
+func parse(t *testing.T, content string) *env.Document {
+	t.Helper()
+
+	doc, err := env.ParseDocument(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return doc
+}
+
+func formatString(t *testing.T, content string, order []string) string {
+	t.Helper()
+
+	formatted, err := format.Format(parse(t, content), order)
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	return formatted.String()
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		order   []string
+		want    string
+	}{
+		{
+			name:    "groups by prefix",
+			content: "REDIS_URL=r\nDB_PORT=5432\nTOKEN=t\nDB_HOST=h\nAPP_NAME=a\nAPP_ENV=dev\n",
+			want:    "REDIS_URL=r\nTOKEN=t\n\nAPP_ENV=dev\nAPP_NAME=a\n\nDB_HOST=h\nDB_PORT=5432\n",
+		},
+		{
+			name:    "schema order first",
+			content: "A=1\nPORT=80\nB=2\nHOST=h\n",
+			order:   []string{"HOST", "PORT"},
+			want:    "HOST=h\nPORT=80\n\nA=1\nB=2\n",
+		},
+		{
+			name:    "comments move with their variable",
+			content: "# the port\nPORT=80\n\n# the host\n# required\nHOST=h\n",
+			want:    "# the host\n# required\nHOST=h\n# the port\nPORT=80\n",
+		},
+		{
+			name:    "header and trailer stay in place",
+			content: "# app settings\n\n# generated\n\nB=2\nA=1\n# end\n",
+			want:    "# app settings\n\n# generated\n\nA=1\nB=2\n\n# end\n",
+		},
+		{
+			name:    "quotes only when needed",
+			content: "A='plain'\nB=\"two words\"\nC='$HOME'\nD=\"${HOME}\"\nE=\\$HOME\nF='x#y'\n",
+			want:    "A=plain\nB=\"two words\"\nC=\"\\$HOME\"\nD=${HOME}\nE=\"\\$HOME\"\nF=\"x#y\"\n",
+		},
+		{
+			name:    "aligns inline comments",
+			content: "LONG=value # three\nA=1 # one\nC=3\nB=long # two\n",
+			want:    "A=1    # one\nB=long # two\nC=3\nLONG=value # three\n",
+		},
+		{
+			name:    "keeps export and repeated keys in order",
+			content: "export B=1\nA=2\nB=3\n",
+			order:   []string{"B", "A"},
+			want:    "export B=1\nB=3\nA=2\n",
+		},
+		{
+			name:    "repeated key does not form a prefix group",
+			content: "REDIS_URL=a\nPORT=1\nREDIS_URL=b\nHOST=h\n",
+			want:    "HOST=h\nPORT=1\nREDIS_URL=a\nREDIS_URL=b\n",
+		},
+		{
+			name:    "keeps CRLF line endings",
+			content: "B=1\r\nA=2\r\n",
+			want:    "A=2\r\nB=1\r\n",
+		},
+		{
+			name:    "empty file",
+			content: "",
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatString(t, tt.content, tt.order); got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormat_KeepsValues(t *testing.T) {
+	content := "# header\n\nZ='single $HOME'\nexport Y=\"multi\nline\" # note\nX=a\\$b\nW=\"tab\\there\"\nV=${Y}-x\n"
+	doc := parse(t, content)
+
+	formatted, err := format.Format(doc, nil)
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	if got, want := formatted.Templates(), doc.Templates(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Templates() = %v, want %v", got, want)
+	}
+
+	if doc.String() != content {
+		t.Errorf("Format() changed its input to %q", doc.String())
+	}
+}
+
+func TestFormat_Idempotent(t *testing.T) {
+	content := "# header\n\nexport Z='single $HOME'\nDB_B=\"multi\nline\" # note\nDB_A=1 # one\n# about x\nX=a\\$b\n\n# end\n"
+	once := formatString(t, content, []string{"X"})
+
+	if twice := formatString(t, once, []string{"X"}); twice != once {
+		t.Errorf("formatting again = %q, want %q", twice, once)
+	}
+}
+
+func TestFormat_InvalidLine(t *testing.T) {
+	if _, err := format.Format(parse(t, "A=1\nnot an assignment\n"), nil); err == nil {
+		t.Error("Format() expected an error for a file with an invalid line")
+	}
+}
@@ -0,0 +1,257 @@
+// Package generate renders documentation and example env files from a schema.
+package generate
+
+import (
+	"fmt"
+	"html/template"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tommyalmeida/envsync/internal/env"
+	"github.com/tommyalmeida/envsync/pkg/schema"
+)
+
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// Example renders a commented env file with a line for every schema variable.
+// Defaults are used as values, except for secrets, which get a placeholder
+// like every variable without a default.
+func Example(s schema.Schema) string {
+	var b strings.Builder
+
+	b.WriteString("# Generated by envsync generate example from the schema. Do not edit.\n")
+
+	for _, name := range variableNames(s) {
+		variable := s.Variables[name]
+
+		b.WriteByte('\n')
+
+		if variable.Description != "" {
+			for _, line := range strings.Split(strings.TrimSpace(variable.Description), "\n") {
+				b.WriteString(strings.TrimRight("# "+line, " ") + "\n")
+			}
+		}
+
+		b.WriteString("# " + strings.Join(attributes(variable), ", ") + "\n")
+
+		doc := env.NewDocument()
+		doc.Set(name, exampleValue(variable))
+		b.WriteString(doc.String())
+	}
+
+	return b.String()
+}
+
+func exampleValue(variable schema.Variable) string {
+	if variable.Default != "" && !variable.Secret {
+		return variable.Default
+	}
+
+	if variable.Type == "enum" && len(variable.Values) > 0 {
+		return "<" + strings.Join(variable.Values, "|") + ">"
+	}
+
+	return "<" + typeName(variable) + ">"
+}
+
+// attributes describes the type, constraints and flags of variable.
+func attributes(variable schema.Variable) []string {
+	attrs := []string{"type: " + typeName(variable)}
+
+	if variable.Required {
+		attrs = append(attrs, "required")
+	}
+
+	if variable.Secret {
+		attrs = append(attrs, "secret")
+	}
+
+	return append(attrs, constraints(variable)...)
+}
+
+func constraints(variable schema.Variable) []string {
+	var result []string
+
+	if len(variable.Values) > 0 {
+		result = append(result, "values: "+strings.Join(variable.Values, ", "))
+	}
+
+	if variable.Min != nil {
+		result = append(result, "min: "+formatNumber(*variable.Min))
+	}
+
+	if variable.Max != nil {
+		result = append(result, "max: "+formatNumber(*variable.Max))
+	}
+
+	if len(variable.Schemes) > 0 {
+		result = append(result, "schemes: "+strings.Join(variable.Schemes, ", "))
+	}
+
+	if variable.Pattern != "" {
+		result = append(result, "pattern: "+variable.Pattern)
+	}
+
+	if len(variable.Requires) > 0 {
+		result = append(result, "requires: "+strings.Join(variable.Requires, ", "))
+	}
+
+	if len(variable.ConflictsWith) > 0 {
+		result = append(result, "conflicts with: "+strings.Join(variable.ConflictsWith, ", "))
+	}
+
+	conditions := make([]string, 0, len(variable.RequiredIf))
+	for condition, value := range variable.RequiredIf {
+		conditions = append(conditions, condition+"="+value)
+	}
+	sort.Strings(conditions)
+
+	if len(conditions) > 0 {
+		result = append(result, "required if: "+strings.Join(conditions, ", "))
+	}
+
+	return result
+}
+
+func typeName(variable schema.Variable) string {
+	if variable.Type == "" {
+		return "string"
+	}
+
+	return variable.Type
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+func variableNames(s schema.Schema) []string {
+	names := make([]string, 0, len(s.Variables))
+
+	for name := range s.Variables {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// docRow is a variable as shown in the reference table.
+type docRow struct {
+	Name        string
+	Type        string
+	Required    bool
+	Secret      bool
+	Default     string
+	Description string
+}
+
+func docRows(s schema.Schema) []docRow {
+	rows := make([]docRow, 0, len(s.Variables))
+
+	for _, name := range variableNames(s) {
+		variable := s.Variables[name]
+
+		kind := typeName(variable)
+		if extra := constraints(variable); len(extra) > 0 {
+			kind += " (" + strings.Join(extra, "; ") + ")"
+		}
+
+		defaultValue := variable.Default
+		if variable.Secret {
+			defaultValue = ""
+		}
+
+		rows = append(rows, docRow{
+			Name:        name,
+			Type:        kind,
+			Required:    variable.Required,
+			Secret:      variable.Secret && variable.Default != "",
+			Default:     defaultValue,
+			Description: strings.Join(strings.Fields(variable.Description), " "),
+		})
+	}
+
+	return rows
+}
+
+// Docs renders a reference table of the schema variables as Markdown or HTML.
+func Docs(s schema.Schema, format string) (string, error) {
+	switch format {
+	case FormatMarkdown, "md", "":
+		return markdownDocs(s), nil
+	case FormatHTML:
+		return htmlDocs(s)
+	default:
+		return "", fmt.Errorf("unknown docs format %q, expected markdown or html", format)
+	}
+}
+
+func markdownDocs(s schema.Schema) string {
+	var b strings.Builder
+
+	b.WriteString("<!-- Generated by envsync generate docs from the schema. Do not edit. -->\n\n")
+	b.WriteString("# Environment variables\n\n")
+	b.WriteString("| Variable | Type | Required | Default | Description |\n")
+	b.WriteString("|----------|------|----------|---------|-------------|\n")
+
+	for _, row := range docRows(s) {
+		required := "no"
+		if row.Required {
+			required = "yes"
+		}
+
+		defaultValue := markdownCode(row.Default)
+		if row.Secret {
+			defaultValue = "(secret)"
+		}
+
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s | %s |\n",
+			row.Name, markdownCell(row.Type), required, defaultValue, markdownCell(row.Description))
+	}
+
+	return b.String()
+}
+
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	return "`" + markdownCell(s) + "`"
+}
+
+// html/template drops comments, so the generated notice is written separately.
+var htmlTemplate = template.Must(template.New("docs").Parse(`<table>
+  <thead>
+    <tr><th>Variable</th><th>Type</th><th>Required</th><th>Default</th><th>Description</th></tr>
+  </thead>
+  <tbody>
+{{- range .}}
+    <tr><td><code>{{.Name}}</code></td><td>{{.Type}}</td><td>{{if .Required}}yes{{else}}no{{end}}</td><td>{{if .Secret}}(secret){{else if .Default}}<code>{{.Default}}</code>{{end}}</td><td>{{.Description}}</td></tr>
+{{- end}}
+  </tbody>
+</table>
+`))
+
+func htmlDocs(s schema.Schema) (string, error) {
+	var b strings.Builder
+
+	b.WriteString("<!-- Generated by envsync generate docs from the schema. Do not edit. -->\n")
+
+	if err := htmlTemplate.Execute(&b, docRows(s)); err != nil {
+		return "", fmt.Errorf("failed to render docs: %w", err)
+	}
+
+	return b.String(), nil
+}
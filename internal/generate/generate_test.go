@@ -0,0 +1,91 @@
+package generate_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tommyalmeida/envsync/internal/env"
+	"github.com/tommyalmeida/envsync/internal/generate"
+	"github.com/tommyalmeida/envsync/pkg/schema"
+)
+
+func testSchema() schema.Schema {
+	minPort := 1024.0
+
+	return schema.Schema{
+		Variables: map[string]schema.Variable{
+			"PORT":      {Required: true, Type: "port", Default: "3000", Min: &minPort, Description: "Server port"},
+			"LOG_LEVEL": {Type: "enum", Values: []string{"debug", "info"}, Description: "Log level | verbosity"},
+			"API_TOKEN": {Required: true, Secret: true, Default: "dev-token"},
+			"GREETING":  {Default: "hello world"},
+		},
+	}
+}
+
+func TestExample(t *testing.T) {
+	expected := `# Generated by envsync generate example from the schema. Do not edit.
+
+# type: string, required, secret
+API_TOKEN=<string>
+
+# type: string
+GREETING="hello world"
+
+# Log level | verbosity
+# type: enum, values: debug, info
+LOG_LEVEL=<debug|info>
+
+# Server port
+# type: port, required, min: 1024
+PORT=3000
+`
+
+	got := generate.Example(testSchema())
+
+	if got != expected {
+		t.Errorf("Example() =\n%s\nwant\n%s", got, expected)
+	}
+
+	doc, err := env.ParseDocument(strings.NewReader(got))
+	if err != nil || doc.Err() != nil {
+		t.Fatalf("example does not parse: %v %v", err, doc.Err())
+	}
+
+	if value, _ := doc.Get("GREETING"); value != "hello world" {
+		t.Errorf("GREETING = %q, want the default", value)
+	}
+}
+
+func TestDocs(t *testing.T) {
+	markdown, err := generate.Docs(testSchema(), generate.FormatMarkdown)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"| `API_TOKEN` | string | yes | (secret) |  |",
+		"| `LOG_LEVEL` | enum (values: debug, info) | no |  | Log level \\| verbosity |",
+		"| `PORT` | port (min: 1024) | yes | `3000` | Server port |",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("markdown docs do not contain %q:\n%s", want, markdown)
+		}
+	}
+
+	if strings.Contains(markdown, "dev-token") {
+		t.Error("markdown docs contain a secret default")
+	}
+
+	html, err := generate.Docs(testSchema(), generate.FormatHTML)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(html, "<!-- Generated") || !strings.Contains(html, "<td><code>PORT</code></td>") {
+		t.Errorf("unexpected html docs:\n%s", html)
+	}
+
+	if _, err := generate.Docs(testSchema(), "pdf"); err == nil {
+		t.Error("expected error for unknown format, got none")
+	}
+}
@@ -0,0 +1,430 @@
+// Package lint checks env files for mistakes that parsers accept silently or
+// read differently, and fixes what can be fixed without changing a value.
+package lint
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/tommyalmeida/envsync/internal/config"
+	"github.com/tommyalmeida/envsync/internal/env"
+	"github.com/tommyalmeida/envsync/pkg/schema"
+)
+
+// Severities of findings, as listed in config.LintSeverities.
+const (
+	SeverityError = "error"
+	SeverityWarn  = "warn"
+	SeverityOff   = "off"
+)
+
+// Rule is a check on an env file.
+type Rule struct {
+	Name        string
+	Description string
+	// Severity is the severity used unless the config sets another.
+	Severity string
+
+	check func(doc *env.Document) []Finding
+	// fix rewrites what check reports without changing any value. It is nil
+	// for rules that cannot be fixed safely.
+	fix func(doc *env.Document)
+}
+
+// Rules are all lint rules, in the order they are applied when fixing.
+var Rules = []Rule{
+	{
+		Name:        "invalid-line",
+		Description: "lines that are not an assignment, a comment or blank",
+		Severity:    SeverityError,
+		check:       checkInvalidLines,
+	},
+	{
+		Name:        "duplicate-key",
+		Description: "keys assigned more than once, where the last assignment wins",
+		Severity:    SeverityError,
+		check:       checkDuplicateKeys,
+		fix:         fixDuplicateKeys,
+	},
+	{
+		Name:        "invalid-key",
+		Description: "keys with dots or dashes, which shells cannot export",
+		Severity:    SeverityWarn,
+		check:       checkInvalidKeys,
+	},
+	{
+		Name:        "key-case",
+		Description: "keys that are not upper case",
+		Severity:    SeverityWarn,
+		check:       checkKeyCase,
+	},
+	{
+		Name:        "trailing-whitespace",
+		Description: "spaces or tabs at the end of a line",
+		Severity:    SeverityWarn,
+		check:       checkTrailingWhitespace,
+		fix:         fixTrailingWhitespace,
+	},
+	{
+		Name:        "unquoted-hash",
+		Description: "unquoted values containing #, which some parsers read as a comment",
+		Severity:    SeverityWarn,
+		check:       checkUnquotedHash,
+		fix:         fixUnquotedHash,
+	},
+	{
+		Name:        "export-prefix",
+		Description: "assignments that use export differently from most of the file",
+		Severity:    SeverityWarn,
+		check:       checkExportPrefix,
+		fix:         fixExportPrefix,
+	},
+}
+
+// RuleNames returns the names of all rules.
+func RuleNames() []string {
+	names := make([]string, len(Rules))
+
+	for i, rule := range Rules {
+		names[i] = rule.Name
+	}
+
+	return names
+}
+
+// Finding is a problem a rule found in a file.
+type Finding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	// Variable is the key of the assignment the finding is about, if any.
+	Variable string          `json:"variable,omitempty"`
+	Position schema.Position `json:"position"`
+	// Fixable reports whether Fix rewrites the problem.
+	Fixable bool `json:"fixable,omitempty"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s (%s)", f.Position, f.Severity, f.Message, f.Rule)
+}
+
+type Linter struct {
+	severities map[string]string
+}
+
+// NewLinter returns a linter that applies severities, by rule name, over the
+// default severity of each rule.
+func NewLinter(severities map[string]string) (*Linter, error) {
+	l := &Linter{severities: make(map[string]string, len(Rules))}
+
+	for _, rule := range Rules {
+		l.severities[rule.Name] = rule.Severity
+	}
+
+	for name, severity := range severities {
+		if _, exists := l.severities[name]; !exists {
+			return nil, fmt.Errorf("unknown lint rule %q, expected one of %s", name, strings.Join(RuleNames(), ", "))
+		}
+
+		if !slices.Contains(config.LintSeverities, severity) {
+			return nil, fmt.Errorf("invalid severity %q for lint rule %s, expected one of %s", severity, name, strings.Join(config.LintSeverities, ", "))
+		}
+
+		l.severities[name] = severity
+	}
+
+	return l, nil
+}
+
+// Lint returns the findings of every enabled rule in file order.
+func (l *Linter) Lint(doc *env.Document) []Finding {
+	var findings []Finding
+
+	for _, rule := range Rules {
+		severity := l.severities[rule.Name]
+		if severity == SeverityOff {
+			continue
+		}
+
+		for _, finding := range rule.check(doc) {
+			finding.Rule = rule.Name
+			finding.Severity = severity
+			finding.Fixable = rule.fix != nil
+			findings = append(findings, finding)
+		}
+	}
+
+	slices.SortStableFunc(findings, func(a, b Finding) int {
+		return cmp.Or(
+			cmp.Compare(a.Position.Line, b.Position.Line),
+			cmp.Compare(a.Position.Column, b.Position.Column),
+		)
+	})
+
+	return findings
+}
+
+// Fix returns a copy of doc rewritten by the fixes of every enabled rule.
+// Values, and what they expand to, are never changed.
+func (l *Linter) Fix(doc *env.Document) (*env.Document, error) {
+	fixed, err := reparse(doc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rule := range Rules {
+		if rule.fix == nil || l.severities[rule.Name] == SeverityOff {
+			continue
+		}
+
+		rule.fix(fixed)
+
+		// Parse the result again so that the next fix sees fresh columns.
+		if fixed, err = reparse(fixed); err != nil {
+			return nil, fmt.Errorf("failed to apply lint rule %s: %w", rule.Name, err)
+		}
+	}
+
+	return fixed, nil
+}
+
+func reparse(doc *env.Document) (*env.Document, error) {
+	parsed, err := env.ParseDocument(strings.NewReader(doc.String()))
+
+	if err != nil {
+		return nil, err
+	}
+
+	parsed.File = doc.File
+
+	return parsed, nil
+}
+
+// HasErrors reports whether any finding has error severity.
+func HasErrors(findings []Finding) bool {
+	return slices.ContainsFunc(findings, func(f Finding) bool { return f.Severity == SeverityError })
+}
+
+func position(doc *env.Document, line *env.Line, column int) schema.Position {
+	return schema.Position{File: doc.File, Line: line.Number, Column: column}
+}
+
+func assignments(doc *env.Document) []*env.Line {
+	var lines []*env.Line
+
+	for _, line := range doc.Lines {
+		if line.Kind == env.LineAssignment {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+func checkInvalidLines(doc *env.Document) []Finding {
+	var findings []Finding
+
+	for _, line := range doc.Lines {
+		if line.Kind == env.LineInvalid {
+			findings = append(findings, Finding{
+				Message:  fmt.Sprintf("cannot parse %q as an assignment", strings.TrimSpace(line.Raw)),
+				Position: position(doc, line, 1),
+			})
+		}
+	}
+
+	return findings
+}
+
+func checkDuplicateKeys(doc *env.Document) []Finding {
+	var findings []Finding
+
+	for _, line := range assignments(doc) {
+		if effective, _ := doc.Lookup(line.Key); effective != line {
+			findings = append(findings, Finding{
+				Variable: line.Key,
+				Message:  fmt.Sprintf("%s is assigned again on line %d, which wins", line.Key, effective.Number),
+				Position: position(doc, line, line.Column),
+			})
+		}
+	}
+
+	return findings
+}
+
+// fixDuplicateKeys removes every assignment that a later one overrides.
+func fixDuplicateKeys(doc *env.Document) {
+	overridden := make(map[*env.Line]bool)
+
+	for _, line := range assignments(doc) {
+		if effective, _ := doc.Lookup(line.Key); effective != line {
+			overridden[line] = true
+		}
+	}
+
+	doc.Lines = slices.DeleteFunc(doc.Lines, func(line *env.Line) bool { return overridden[line] })
+}
+
+func checkInvalidKeys(doc *env.Document) []Finding {
+	var findings []Finding
+
+	for _, line := range assignments(doc) {
+		if strings.ContainsAny(line.Key, ".-") {
+			findings = append(findings, Finding{
+				Variable: line.Key,
+				Message:  fmt.Sprintf("%s is not a valid shell variable name", line.Key),
+				Position: position(doc, line, line.Column),
+			})
+		}
+	}
+
+	return findings
+}
+
+func checkKeyCase(doc *env.Document) []Finding {
+	var findings []Finding
+
+	for _, line := range assignments(doc) {
+		if line.Key != strings.ToUpper(line.Key) {
+			findings = append(findings, Finding{
+				Variable: line.Key,
+				Message:  fmt.Sprintf("%s is not upper case", line.Key),
+				Position: position(doc, line, line.Column),
+			})
+		}
+	}
+
+	return findings
+}
+
+func checkTrailingWhitespace(doc *env.Document) []Finding {
+	var findings []Finding
+
+	for _, line := range doc.Lines {
+		// Only the last physical line can end outside a quoted value.
+		lines := strings.Split(line.Raw, "\n")
+		last := lines[len(lines)-1]
+		trimmed := strings.TrimRight(last, " \t")
+
+		if trimmed == last {
+			continue
+		}
+
+		findings = append(findings, Finding{
+			Variable: line.Key,
+			Message:  "trailing whitespace",
+			Position: schema.Position{
+				File:   doc.File,
+				Line:   line.Number + len(lines) - 1,
+				Column: len(trimmed) + 1,
+			},
+		})
+	}
+
+	return findings
+}
+
+func fixTrailingWhitespace(doc *env.Document) {
+	for _, line := range doc.Lines {
+		line.Raw = strings.TrimRight(line.Raw, " \t")
+	}
+}
+
+func checkUnquotedHash(doc *env.Document) []Finding {
+	var findings []Finding
+
+	for _, line := range assignments(doc) {
+		if line.Quote == 0 && strings.Contains(line.Value, "#") {
+			findings = append(findings, Finding{
+				Variable: line.Key,
+				Message:  fmt.Sprintf("the value of %s contains # without quotes", line.Key),
+				Position: position(doc, line, valueOffset(line)+1),
+			})
+		}
+	}
+
+	return findings
+}
+
+// fixUnquotedHash puts such values in double quotes. The quoted value has
+// the same template, so escaped dollar signs stay escaped and references
+// still expand.
+func fixUnquotedHash(doc *env.Document) {
+	for _, line := range assignments(doc) {
+		if line.Quote != 0 || !strings.Contains(line.Value, "#") {
+			continue
+		}
+
+		offset := valueOffset(line)
+		line.Raw = line.Raw[:offset] + env.QuoteTemplate(line.Template()) + line.Raw[offset+len(line.Value):]
+	}
+}
+
+// valueOffset returns the byte offset of the value in the raw text of an
+// assignment.
+func valueOffset(line *env.Line) int {
+	afterKey := line.Raw[line.Column-1+len(line.Key):]
+	afterEquals := afterKey[strings.IndexByte(afterKey, '=')+1:]
+
+	return len(line.Raw) - len(strings.TrimLeft(afterEquals, " \t"))
+}
+
+// exportMajority reports whether most assignments use export. Ties go to no
+// export.
+func exportMajority(doc *env.Document) bool {
+	exported, total := 0, 0
+
+	for _, line := range assignments(doc) {
+		total++
+		if line.Export {
+			exported++
+		}
+	}
+
+	return exported*2 > total
+}
+
+func checkExportPrefix(doc *env.Document) []Finding {
+	var findings []Finding
+	majority := exportMajority(doc)
+
+	for _, line := range assignments(doc) {
+		if line.Export == majority {
+			continue
+		}
+
+		message := fmt.Sprintf("%s has no export prefix, unlike most assignments", line.Key)
+		if line.Export {
+			message = fmt.Sprintf("%s has an export prefix, unlike most assignments", line.Key)
+		}
+
+		findings = append(findings, Finding{
+			Variable: line.Key,
+			Message:  message,
+			Position: position(doc, line, len(line.Indent)+1),
+		})
+	}
+
+	return findings
+}
+
+func fixExportPrefix(doc *env.Document) {
+	majority := exportMajority(doc)
+
+	for _, line := range assignments(doc) {
+		if line.Export == majority {
+			continue
+		}
+
+		indent := len(line.Indent)
+
+		if majority {
+			line.Raw = line.Raw[:indent] + "export " + line.Raw[indent:]
+		} else {
+			line.Raw = line.Raw[:indent] + line.Raw[line.Column-1:]
+		}
+	}
+}
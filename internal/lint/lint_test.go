@@ -0,0 +1,178 @@
+package lint_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tommyalmeida/envsync/internal/env"
+	"github.com/tommyalmeida/envsync/internal/lint"
+)
+
+const messyEnv = "export A=1\n" +
+	"export B=2   \n" +
+	"C=x#y # note\n" +
+	"A=3\n" +
+	"db.host=x\n" +
+	"bad line\n" +
+	"D=\\$HOME#\"1\" \n" +
+	"E=\"multi  \n" +
+	"line\"\n"
+
+func parse(t *testing.T, content string) *env.Document {
+	t.Helper()
+
+	doc, err := env.ParseDocument(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	doc.File = ".env"
+
+	return doc
+}
+
+func newLinter(t *testing.T, severities map[string]string) *lint.Linter {
+	t.Helper()
+
+	linter, err := lint.NewLinter(severities)
+	if err != nil {
+		t.Fatalf("NewLinter() error = %v", err)
+	}
+
+	return linter
+}
+
+func TestLinter_Lint(t *testing.T) {
+	findings := newLinter(t, nil).Lint(parse(t, messyEnv))
+
+	var got []string
+	for _, finding := range findings {
+		got = append(got, finding.String())
+	}
+
+	expected := []string{
+		".env:1:1: warn: A has an export prefix, unlike most assignments (export-prefix)",
+		".env:1:8: error: A is assigned again on line 4, which wins (duplicate-key)",
+		".env:2:1: warn: B has an export prefix, unlike most assignments (export-prefix)",
+		".env:2:11: warn: trailing whitespace (trailing-whitespace)",
+		".env:3:3: warn: the value of C contains # without quotes (unquoted-hash)",
+		".env:5:1: warn: db.host is not a valid shell variable name (invalid-key)",
+		".env:5:1: warn: db.host is not upper case (key-case)",
+		`.env:6:1: error: cannot parse "bad line" as an assignment (invalid-line)`,
+		".env:7:3: warn: the value of D contains # without quotes (unquoted-hash)",
+		".env:7:13: warn: trailing whitespace (trailing-whitespace)",
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	if !lint.HasErrors(findings) {
+		t.Error("expected errors")
+	}
+}
+
+func TestLinter_Severities(t *testing.T) {
+	linter := newLinter(t, map[string]string{
+		"duplicate-key":       "warn",
+		"invalid-line":        "off",
+		"export-prefix":       "off",
+		"trailing-whitespace": "error",
+	})
+
+	findings := linter.Lint(parse(t, "A=1  \nA=2\nbad line\n"))
+
+	var got []string
+	for _, finding := range findings {
+		got = append(got, finding.Rule+":"+finding.Severity)
+	}
+
+	expected := []string{"duplicate-key:warn", "trailing-whitespace:error"}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestNewLinter_Invalid(t *testing.T) {
+	tests := []struct {
+		severities map[string]string
+		want       string
+	}{
+		{map[string]string{"no-such-rule": "warn"}, `unknown lint rule "no-such-rule"`},
+		{map[string]string{"key-case": "fatal"}, `invalid severity "fatal" for lint rule key-case`},
+	}
+
+	for _, tt := range tests {
+		_, err := lint.NewLinter(tt.severities)
+
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("expected error containing %q, got %v", tt.want, err)
+		}
+	}
+}
+
+func TestLinter_Fix(t *testing.T) {
+	doc := parse(t, messyEnv)
+	linter := newLinter(t, nil)
+
+	fixed, err := linter.Fix(doc)
+	if err != nil {
+		t.Fatalf("Fix() error = %v", err)
+	}
+
+	expected := "B=2\n" +
+		"C=\"x#y\" # note\n" +
+		"A=3\n" +
+		"db.host=x\n" +
+		"bad line\n" +
+		"D=\"\\$HOME#\\\"1\\\"\"\n" +
+		"E=\"multi  \n" +
+		"line\"\n"
+
+	if got := fixed.String(); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	if !reflect.DeepEqual(fixed.Vars(), parse(t, expected).Vars()) {
+		t.Error("expected the fixed document to parse the same")
+	}
+
+	before, err := env.Expand(doc.Templates(), env.ExpandOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	after, err := env.Expand(fixed.Templates(), env.ExpandOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		t.Errorf("fix changed values:\nbefore %v\nafter  %v", before, after)
+	}
+
+	if doc.String() != messyEnv {
+		t.Error("expected Fix to leave its input unchanged")
+	}
+
+	for _, finding := range linter.Lint(fixed) {
+		if finding.Fixable {
+			t.Errorf("expected %s to be fixed", finding)
+		}
+	}
+}
+
+func TestLinter_FixAddsExport(t *testing.T) {
+	fixed, err := newLinter(t, nil).Fix(parse(t, "export A=1\n  B=2\nexport C=3\n"))
+	if err != nil {
+		t.Fatalf("Fix() error = %v", err)
+	}
+
+	expected := "export A=1\n  export B=2\nexport C=3\n"
+
+	if got := fixed.String(); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
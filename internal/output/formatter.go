@@ -1,16 +1,26 @@
 package output
 
 import (
+	"encoding/json"
 	"fmt"
-	"log"
-	"os"
+	"io"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/fatih/color"
 
 	"github.com/tommyalmeida/envsync/internal/env"
+	"github.com/tommyalmeida/envsync/internal/lint"
 )
 
+// Formatter prints results for people. Reports go to stdout; notes that
+// must not mix with data written to stdout, such as the merge report, go to
+// stderr. It never exits: the caller decides the exit code from the result.
 type Formatter struct {
+	stdout   io.Writer
+	stderr   io.Writer
 	useColor bool
 
 	red    func(a ...interface{}) string
@@ -20,8 +30,8 @@ type Formatter struct {
 	bold   func(a ...interface{}) string
 }
 
-func NewFormatter(useColor bool) *Formatter {
-	f := &Formatter{useColor: useColor}
+func NewFormatter(stdout, stderr io.Writer, useColor bool) *Formatter {
+	f := &Formatter{stdout: stdout, stderr: stderr, useColor: useColor}
 
 	if useColor {
 		f.red = color.New(color.FgRed).SprintFunc()
@@ -40,71 +50,184 @@ func NewFormatter(useColor bool) *Formatter {
 	return f
 }
 
+// Stdout returns the writer reports are printed to, for commands that write
+// data such as generated files rather than results.
+func (f *Formatter) Stdout() io.Writer {
+	return f.stdout
+}
+
+// Stderr returns the writer notes are printed to.
+func (f *Formatter) Stderr() io.Writer {
+	return f.stderr
+}
+
+// PrintJSON prints v to stdout as indented JSON.
+func (f *Formatter) PrintJSON(v any) error {
+	encoder := json.NewEncoder(f.stdout)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+
+	return encoder.Encode(v)
+}
+
+// PrintReports prints reports to stdout in format, one of Formats.
+func (f *Formatter) PrintReports(format string, reports ...Report) error {
+	return WriteReports(f.stdout, format, reports...)
+}
+
 func (f *Formatter) PrintValidationResult(result env.ValidationResult) error {
 	if result.Valid {
-		fmt.Println(f.green("✓ Validation passed"))
+		fmt.Fprintln(f.stdout, f.green("✓ Validation passed"))
+		return nil
+	}
+
+	fmt.Fprintln(f.stdout, f.red("✗ Validation failed"))
+
+	f.printValidationDetails(result)
+
+	return nil
+}
+
+// PrintValidationResults prints the result for every environment in names and
+// a summary.
+func (f *Formatter) PrintValidationResults(names []string, results map[string]env.ValidationResult) error {
+	failed := 0
+
+	for _, name := range names {
+		result := results[name]
+
+		if result.Valid {
+			fmt.Fprintf(f.stdout, "%s %s\n", f.green("✓"), f.bold(name))
+			continue
+		}
+
+		failed++
+		fmt.Fprintf(f.stdout, "%s %s\n", f.red("✗"), f.bold(name))
+		f.printValidationDetails(result)
+	}
+
+	if failed == 0 {
+		fmt.Fprintln(f.stdout, f.green("✓ Validation passed"))
 		return nil
 	}
 
-	fmt.Println(f.red("✗ Validation failed"))
+	fmt.Fprintln(f.stdout, f.red(fmt.Sprintf("✗ Validation failed for %d of %d environments", failed, len(names))))
 
+	return nil
+}
+
+func (f *Formatter) printValidationDetails(result env.ValidationResult) {
 	if len(result.Missing) > 0 {
-		log.Printf("\n%s:\n", f.bold("Missing required variables"))
+		fmt.Fprintf(f.stdout, "\n%s:\n", f.bold("Missing required variables"))
 		for _, variable := range result.Missing {
-			log.Printf("  - %s\n", f.red(variable))
+			fmt.Fprintf(f.stdout, "  - %s\n", f.red(variable))
 		}
 	}
 
 	if len(result.Errors) > 0 {
-		log.Printf("\n%s:\n", f.bold("Validation errors"))
+		fmt.Fprintf(f.stdout, "\n%s:\n", f.bold("Validation errors"))
 		for _, err := range result.Errors {
-			log.Printf("  - %s: %s\n", f.red(err.Variable), err.Message)
+			if err.Position != nil {
+				fmt.Fprintf(f.stdout, "  - %s: %s (%s)\n", f.red(err.Variable), err.Message, err.Position)
+				continue
+			}
+
+			fmt.Fprintf(f.stdout, "  - %s: %s\n", f.red(err.Variable), err.Message)
 		}
 	}
 
 	if len(result.Extra) > 0 {
-		log.Printf("\n%s:\n", f.bold("Extra variables (not in schema)"))
+		fmt.Fprintf(f.stdout, "\n%s:\n", f.bold("Extra variables (not in schema)"))
 		for _, variable := range result.Extra {
-			log.Printf("  - %s\n", f.yellow(variable))
+			fmt.Fprintf(f.stdout, "  - %s\n", f.yellow(variable))
 		}
 	}
+}
 
-	os.Exit(1)
-	return nil
+// PrintProvenance prints the config file each schema variable comes from and
+// the files whose definitions it overrides.
+func (f *Formatter) PrintProvenance(provenance map[string][]string) {
+	if len(provenance) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(provenance))
+	width := 0
+
+	for name := range provenance {
+		names = append(names, name)
+		width = max(width, len(name))
+	}
+
+	sort.Strings(names)
+
+	fmt.Fprintf(f.stderr, "%s:\n", f.bold("Schema sources"))
+
+	for _, name := range names {
+		files := provenance[name]
+		source := files[len(files)-1]
+
+		if len(files) > 1 {
+			overridden := slices.Clone(files[:len(files)-1])
+			slices.Reverse(overridden)
+			source += f.blue(" (overrides " + strings.Join(overridden, ", ") + ")")
+		}
+
+		fmt.Fprintf(f.stderr, "  %s  %s\n", pad(name, width), source)
+	}
 }
 
 func (f *Formatter) PrintDiff(diff env.DiffResult, sourceFile, targetFile string) error {
-	log.Printf("%s vs %s\n\n", f.bold(sourceFile), f.bold(targetFile))
+	fmt.Fprintf(f.stdout, "%s vs %s\n\n", f.bold(sourceFile), f.bold(targetFile))
 
-	hasChanges := len(diff.Missing) > 0 || len(diff.Extra) > 0 || len(diff.Different) > 0
+	var disallowed []string
+	for _, match := range diff.Rules {
+		if match.Rule == env.RuleAllowExtra {
+			disallowed = append(disallowed, match.Variable)
+		}
+	}
+
+	hasChanges := len(diff.Missing) > 0 || len(diff.Extra) > 0 || len(diff.Different) > 0 || len(disallowed) > 0
 
 	if !hasChanges {
-		fmt.Println(f.green("✓ Files are in sync"))
+		fmt.Fprintln(f.stdout, f.green("✓ Files are in sync"))
 		return nil
 	}
 
 	if len(diff.Missing) > 0 {
-		log.Printf("%s (%d):\n", f.bold("Missing in target"), len(diff.Missing))
+		fmt.Fprintf(f.stdout, "%s (%d):\n", f.bold("Missing in target"), len(diff.Missing))
 		for _, key := range diff.Missing {
-			log.Printf("  %s %s\n", f.red("-"), key)
+			fmt.Fprintf(f.stdout, "  %s %s\n", f.red("-"), key)
 		}
-		fmt.Println()
+		fmt.Fprintln(f.stdout)
 	}
 
 	if len(diff.Extra) > 0 {
-		log.Printf("%s (%d):\n", f.bold("Extra in target"), len(diff.Extra))
+		fmt.Fprintf(f.stdout, "%s (%d):\n", f.bold("Extra in target"), len(diff.Extra))
 		for _, key := range diff.Extra {
-			log.Printf("  %s %s\n", f.green("+"), key)
+			fmt.Fprintf(f.stdout, "  %s %s\n", f.green("+"), key)
 		}
-		fmt.Println()
+		fmt.Fprintln(f.stdout)
 	}
 
 	if len(diff.Different) > 0 {
-		log.Printf("%s (%d):\n", f.bold("Different values"), len(diff.Different))
-		for key, values := range diff.Different {
-			log.Printf("  %s %s\n", f.yellow("~"), key)
-			log.Printf("    %s: %s\n", f.blue("source"), values.Source)
-			log.Printf("    %s: %s\n", f.blue("target"), values.Target)
+		fmt.Fprintf(f.stdout, "%s (%d):\n", f.bold("Different values"), len(diff.Different))
+		for _, key := range slices.Sorted(maps.Keys(diff.Different)) {
+			values := diff.Different[key]
+			fmt.Fprintf(f.stdout, "  %s %s\n", f.yellow("~"), key)
+			fmt.Fprintf(f.stdout, "    %s: %s\n", f.blue("source"), values.Source)
+			fmt.Fprintf(f.stdout, "    %s: %s\n", f.blue("target"), values.Target)
+		}
+	}
+
+	if len(disallowed) > 0 {
+		if len(diff.Different) > 0 {
+			fmt.Fprintln(f.stdout)
+		}
+
+		fmt.Fprintf(f.stdout, "%s (%d):\n", f.bold("Not in schema, extra variables are not allowed"), len(disallowed))
+		for _, key := range disallowed {
+			fmt.Fprintf(f.stdout, "  %s %s\n", f.red("!"), key)
 		}
 	}
 
@@ -117,22 +240,192 @@ func (f *Formatter) PrintSyncResult(result env.SyncResult, dryRun bool) error {
 		action = "Would sync"
 	}
 
-	if len(result.Added) == 0 {
-		fmt.Println(f.green("✓ No variables need to be synced"))
+	if !result.Changed() {
+		f.printSyncDecisions(result, env.ActionSkipped)
+		fmt.Fprintln(f.stdout, f.green("✓ No variables need to be synced"))
 		return nil
 	}
 
-	log.Printf("%s %d variables to %s:\n\n", action, len(result.Added), f.bold(result.FilePath))
+	changes := len(result.Added) + len(result.Updated) + len(result.Removed)
+	fmt.Fprintf(f.stdout, "%s %d variables to %s:\n\n", action, changes, f.bold(result.FilePath))
 
-	for _, key := range result.Added {
-		log.Printf("  %s %s\n", f.green("+"), key)
-	}
+	f.printSyncDecisions(result, env.ActionAdded, env.ActionUpdated, env.ActionRemoved, env.ActionSkipped)
 
 	if dryRun {
-		log.Printf("\n%s\n", f.yellow("This was a dry run. Use --dry-run=false to apply changes."))
+		fmt.Fprintf(f.stdout, "\n%s\n", f.yellow("This was a dry run. Use --dry-run=false to apply changes."))
 	} else {
-		log.Printf("\n%s\n", f.green("✓ Sync completed successfully"))
+		fmt.Fprintf(f.stdout, "\n%s\n", f.green("✓ Sync completed successfully"))
 	}
 
 	return nil
 }
+
+// printSyncDecisions prints the decisions with one of actions, in the order
+// of actions, with their reasons.
+func (f *Formatter) printSyncDecisions(result env.SyncResult, actions ...string) {
+	width := 0
+	for _, decision := range result.Decisions {
+		width = max(width, len(decision.Variable))
+	}
+
+	for _, action := range actions {
+		for _, decision := range result.Decisions {
+			if decision.Action != action {
+				continue
+			}
+
+			fmt.Fprintf(f.stdout, "  %s %s  %s\n", f.syncMarker(action), pad(decision.Variable, width), f.blue(decision.Reason))
+		}
+	}
+}
+
+func (f *Formatter) syncMarker(action string) string {
+	switch action {
+	case env.ActionAdded:
+		return f.green("+")
+	case env.ActionUpdated:
+		return f.yellow("~")
+	case env.ActionRemoved:
+		return f.red("-")
+	default:
+		return f.blue("=")
+	}
+}
+
+// PrintLintFindings prints lint findings in file order and a summary.
+func (f *Formatter) PrintLintFindings(findings []lint.Finding) error {
+	fixable := 0
+
+	for _, finding := range findings {
+		severity := f.yellow(finding.Severity)
+		if finding.Severity == lint.SeverityError {
+			severity = f.red(finding.Severity)
+		}
+
+		fmt.Fprintf(f.stdout, "%s: %s: %s %s\n", finding.Position, severity, finding.Message, f.blue("("+finding.Rule+")"))
+
+		if finding.Fixable {
+			fixable++
+		}
+	}
+
+	switch {
+	case len(findings) == 0:
+		fmt.Fprintln(f.stdout, f.green("✓ No problems found"))
+	case fixable > 0:
+		fmt.Fprintln(f.stdout, f.red(fmt.Sprintf("✗ %d problem(s) found, %d fixable with --fix", len(findings), fixable)))
+	default:
+		fmt.Fprintln(f.stdout, f.red(fmt.Sprintf("✗ %d problem(s) found", len(findings))))
+	}
+
+	return nil
+}
+
+// PrintMergeResult reports the changes a merge applied and its conflicts on
+// stderr, so that the merged file can be written to stdout.
+func (f *Formatter) PrintMergeResult(result env.MergeResult, outputFile string) error {
+	width := 0
+	for _, change := range result.Changes {
+		width = max(width, len(change.Variable))
+	}
+
+	for _, change := range result.Changes {
+		fmt.Fprintf(f.stderr, "  %s %s  %s\n", f.syncMarker(change.Action), pad(change.Variable, width), f.blue(change.Action+" in "+change.Side))
+	}
+
+	if len(result.Conflicts) == 0 {
+		_, err := fmt.Fprintf(f.stderr, "%s\n", f.green(fmt.Sprintf("✓ Merged %d changes into %s", len(result.Changes), outputFile)))
+		return err
+	}
+
+	fmt.Fprintf(f.stderr, "\n%s (%d):\n", f.bold("Conflicts"), len(result.Conflicts))
+
+	for _, conflict := range result.Conflicts {
+		fmt.Fprintf(f.stderr, "  %s %s\n", f.red("!"), conflict.Variable)
+		fmt.Fprintf(f.stderr, "    %s: %s\n", f.blue("base"), mergeValue(conflict.Base))
+		fmt.Fprintf(f.stderr, "    %s: %s\n", f.blue("ours"), mergeValue(conflict.Ours))
+		fmt.Fprintf(f.stderr, "    %s: %s\n", f.blue("theirs"), mergeValue(conflict.Theirs))
+	}
+
+	_, err := fmt.Fprintf(f.stderr, "\n%s\n", f.red(fmt.Sprintf("✗ %d conflicts written to %s", len(result.Conflicts), outputFile)))
+	return err
+}
+
+func mergeValue(value *string) string {
+	if value == nil {
+		return "(not set)"
+	}
+
+	return *value
+}
+
+// PrintMatrix prints a key × environment table. Only keys that are missing
+// somewhere or have differing values are listed unless all is set.
+func (f *Formatter) PrintMatrix(matrix env.MatrixResult, all bool) error {
+	if len(matrix.Environments) == 0 {
+		return nil
+	}
+
+	rows := matrix.Inconsistent()
+
+	if len(rows) == 0 {
+		fmt.Fprintln(f.stdout, f.green(fmt.Sprintf("✓ All %d environments are in sync", len(matrix.Environments))))
+
+		if !all {
+			return nil
+		}
+	}
+
+	if all {
+		rows = matrix.Rows
+	}
+
+	keyWidth := len("KEY")
+	for _, row := range rows {
+		keyWidth = max(keyWidth, len(row.Key))
+	}
+
+	// The last column is not padded to avoid trailing whitespace.
+	widths := make([]int, len(matrix.Environments))
+	for i, name := range matrix.Environments[:len(widths)-1] {
+		widths[i] = max(len(name), 2)
+	}
+
+	fmt.Fprintf(f.stdout, "%s (%d of %d keys differ):\n\n", f.bold("Key matrix"), len(matrix.Inconsistent()), len(matrix.Rows))
+
+	fmt.Fprint(f.stdout, f.bold(pad("KEY", keyWidth)))
+	for i, name := range matrix.Environments {
+		fmt.Fprint(f.stdout, "  "+f.bold(pad(name, widths[i])))
+	}
+	fmt.Fprintln(f.stdout)
+
+	for _, row := range rows {
+		fmt.Fprint(f.stdout, pad(row.Key, keyWidth))
+
+		for i, cell := range row.Cells {
+			fmt.Fprint(f.stdout, "  "+f.matrixCell(cell, row.Consistent, widths[i]))
+		}
+
+		fmt.Fprintln(f.stdout)
+	}
+
+	fmt.Fprintf(f.stdout, "\n%s\n", f.blue("- missing, letters group environments with the same value"))
+
+	return nil
+}
+
+func (f *Formatter) matrixCell(cell env.MatrixCell, consistent bool, width int) string {
+	switch {
+	case !cell.Present:
+		return f.red(pad("-", width))
+	case consistent:
+		return f.green(pad(cell.Group, width))
+	default:
+		return f.yellow(pad(cell.Group, width))
+	}
+}
+
+// pad pads s to width before colouring so escape codes do not skew alignment.
+func pad(s string, width int) string {
+	return fmt.Sprintf("%-*s", width, s)
+}
@@ -0,0 +1,180 @@
+package output_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/tommyalmeida/envsync/internal/env"
+	"github.com/tommyalmeida/envsync/internal/lint"
+	"github.com/tommyalmeida/envsync/internal/output"
+	"github.com/tommyalmeida/envsync/pkg/schema"
+)
+
+func newFormatter() (*output.Formatter, *bytes.Buffer, *bytes.Buffer) {
+	var stdout, stderr bytes.Buffer
+	return output.NewFormatter(&stdout, &stderr, false), &stdout, &stderr
+}
+
+func TestFormatter_PrintValidationResult(t *testing.T) {
+	f, stdout, stderr := newFormatter()
+
+	result := env.ValidationResult{
+		Missing: []string{"DATABASE_URL"},
+		Errors:  []schema.ValidationError{{Variable: "PORT", Message: "not a valid port"}},
+		Extra:   []string{"LEGACY"},
+	}
+
+	if err := f.PrintValidationResult(result); err != nil {
+		t.Fatalf("PrintValidationResult() error = %v", err)
+	}
+
+	expected := `✗ Validation failed
+
+Missing required variables:
+  - DATABASE_URL
+
+Validation errors:
+  - PORT: not a valid port
+
+Extra variables (not in schema):
+  - LEGACY
+`
+	if stdout.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, stdout.String())
+	}
+
+	if stderr.Len() > 0 {
+		t.Errorf("expected nothing on stderr, got %q", stderr.String())
+	}
+}
+
+func TestFormatter_PrintDiff(t *testing.T) {
+	diff := env.DiffResult{
+		Missing: []string{"A"},
+		Different: map[string]env.Diff{
+			"ZETA":  {Source: "1", Target: "2"},
+			"ALPHA": {Source: "x", Target: "y"},
+			"MID":   {Source: "m", Target: "n"},
+		},
+	}
+
+	expected := `.env.example vs .env
+
+Missing in target (1):
+  - A
+
+Different values (3):
+  ~ ALPHA
+    source: x
+    target: y
+  ~ MID
+    source: m
+    target: n
+  ~ ZETA
+    source: 1
+    target: 2
+`
+
+	// Map iteration order is random, so a few runs catch unsorted output.
+	for range 10 {
+		f, stdout, _ := newFormatter()
+
+		if err := f.PrintDiff(diff, ".env.example", ".env"); err != nil {
+			t.Fatalf("PrintDiff() error = %v", err)
+		}
+
+		if stdout.String() != expected {
+			t.Fatalf("expected:\n%s\ngot:\n%s", expected, stdout.String())
+		}
+	}
+}
+
+func TestFormatter_PrintMergeResult(t *testing.T) {
+	f, stdout, stderr := newFormatter()
+
+	ours := "b"
+	err := f.PrintMergeResult(env.MergeResult{
+		Changes:   []env.MergeChange{{Variable: "NEW", Side: env.SideTheirs, Action: env.ActionAdded}},
+		Conflicts: []env.MergeConflict{{Variable: "PORT", Ours: &ours}},
+	}, "-")
+	if err != nil {
+		t.Fatalf("PrintMergeResult() error = %v", err)
+	}
+
+	if stdout.Len() > 0 {
+		t.Errorf("expected the report on stderr only, got %q on stdout", stdout.String())
+	}
+
+	for _, want := range []string{"NEW  added in theirs", "! PORT", "ours: b", "theirs: (not set)", "1 conflicts written to -"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("expected stderr to contain %q, got:\n%s", want, stderr.String())
+		}
+	}
+}
+
+func TestFormatter_PrintJSON(t *testing.T) {
+	f, stdout, stderr := newFormatter()
+
+	if err := f.PrintJSON(map[string]string{"url": "http://a/?b=1&c=<d>"}); err != nil {
+		t.Fatalf("PrintJSON() error = %v", err)
+	}
+
+	expected := "{\n  \"url\": \"http://a/?b=1&c=<d>\"\n}\n"
+	if stdout.String() != expected {
+		t.Errorf("expected %q, got %q", expected, stdout.String())
+	}
+
+	if stderr.Len() > 0 {
+		t.Errorf("expected nothing on stderr, got %q", stderr.String())
+	}
+}
+
+func TestFormatter_PrintLintFindings(t *testing.T) {
+	tests := []struct {
+		name     string
+		findings []lint.Finding
+		expected string
+	}{
+		{
+			name:     "no findings",
+			expected: "✓ No problems found\n",
+		},
+		{
+			name: "fixable findings",
+			findings: []lint.Finding{
+				{Rule: "duplicate-key", Severity: lint.SeverityError, Message: "A is assigned again", Position: schema.Position{File: ".env", Line: 1, Column: 1}},
+				{Rule: "trailing-whitespace", Severity: lint.SeverityWarn, Message: "trailing whitespace", Position: schema.Position{File: ".env", Line: 2, Column: 4}, Fixable: true},
+			},
+			expected: `.env:1:1: error: A is assigned again (duplicate-key)
+.env:2:4: warn: trailing whitespace (trailing-whitespace)
+✗ 2 problem(s) found, 1 fixable with --fix
+`,
+		},
+		{
+			name: "unfixable findings",
+			findings: []lint.Finding{
+				{Rule: "key-case", Severity: lint.SeverityWarn, Message: "b is not upper case", Position: schema.Position{File: ".env", Line: 4, Column: 1}},
+			},
+			expected: ".env:4:1: warn: b is not upper case (key-case)\n✗ 1 problem(s) found\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, stdout, stderr := newFormatter()
+
+			if err := f.PrintLintFindings(tt.findings); err != nil {
+				t.Fatalf("PrintLintFindings() error = %v", err)
+			}
+
+			if stdout.String() != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, stdout.String())
+			}
+
+			if stderr.Len() > 0 {
+				t.Errorf("expected nothing on stderr, got %q", stderr.String())
+			}
+		})
+	}
+}
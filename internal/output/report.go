@@ -0,0 +1,577 @@
+package output
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/tommyalmeida/envsync/internal/env"
+	"github.com/tommyalmeida/envsync/internal/lint"
+	"github.com/tommyalmeida/envsync/pkg/schema"
+)
+
+// Report formats for CI systems, next to the text and JSON output.
+const (
+	FormatText     = "text"
+	FormatJSON     = "json"
+	FormatSARIF    = "sarif"
+	FormatJUnit    = "junit"
+	FormatGitHub   = "github"
+	FormatTAP      = "tap"
+	FormatMarkdown = "markdown"
+)
+
+// Formats lists every value accepted by --format.
+var Formats = []string{FormatText, FormatJSON, FormatSARIF, FormatJUnit, FormatGitHub, FormatTAP, FormatMarkdown}
+
+// Finding levels, named as in SARIF. Only errors fail a JUnit or TAP check.
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+	LevelNote    = "note"
+)
+
+// Report is the outcome of one command on one file, as a list of checks.
+type Report struct {
+	// Name describes the run, such as "validate .env".
+	Name string
+	// File is the file the findings point at.
+	File   string
+	Checks []Check
+}
+
+// Check is one variable of a report. It passed when it has no findings.
+type Check struct {
+	Variable string
+	Findings []Finding
+}
+
+// Finding is something a check found. Line and Column are the position of
+// the variable in File, or 0 when it is not known.
+type Finding struct {
+	Rule    string
+	Level   string
+	Message string
+	Line    int
+	Column  int
+}
+
+// at places the finding at the position of a variable.
+func (f Finding) at(position schema.Position) Finding {
+	f.Line, f.Column = position.Line, position.Column
+	return f
+}
+
+// Rules are the ids of findings, with the description SARIF shows for them.
+// Lint findings use the names of lint.Rules instead.
+var Rules = map[string]string{
+	"missing":       "A required variable is missing",
+	"invalid":       "A variable does not satisfy the schema",
+	"extra":         "A variable is not in the schema",
+	"missing-diff":  "A variable of the source is missing in the target",
+	"extra-diff":    "A variable of the target is not in the source",
+	"different":     "A variable has a different value in the source and target",
+	"missing-env":   "A variable set in another file is missing",
+	"different-env": "A variable has a different value in another file",
+	"sync":          "A variable was synced or skipped",
+}
+
+// ValidationReport turns a validation result into a report with a check for
+// every name in variables. positions locates the variables in file; errors
+// that carry their own position use that instead.
+func ValidationReport(file string, result env.ValidationResult, variables []string, positions env.Positions) Report {
+	findings := make(map[string][]Finding)
+
+	for _, name := range result.Missing {
+		findings[name] = append(findings[name], Finding{Rule: "missing", Level: LevelError, Message: "required variable is missing"})
+	}
+
+	for _, err := range result.Errors {
+		position := positions[err.Variable]
+		if err.Position != nil {
+			position = *err.Position
+		}
+
+		findings[err.Variable] = append(findings[err.Variable], Finding{Rule: "invalid", Level: LevelError, Message: err.Message}.at(position))
+	}
+
+	enforced := make(map[string]bool)
+	for _, match := range result.Rules {
+		if match.Rule == env.RuleAllowExtra {
+			enforced[match.Variable] = true
+		}
+	}
+
+	for _, name := range result.Extra {
+		level := LevelWarning
+		if enforced[name] {
+			level = LevelError
+		}
+
+		findings[name] = append(findings[name], Finding{Rule: "extra", Level: level, Message: "variable is not in the schema"}.at(positions[name]))
+	}
+
+	return newReport("validate "+file, file, variables, findings)
+}
+
+// DiffReport turns a comparison of source and target into a report on
+// target. positions locates the variables in target.
+func DiffReport(source, target string, diff env.DiffResult, positions env.Positions) Report {
+	findings := make(map[string][]Finding)
+	variables := slices.Concat(diff.Same, diff.Missing, diff.Extra)
+
+	required := make(map[string]bool)
+	for _, match := range diff.Rules {
+		switch match.Rule {
+		case env.RuleRequireAll:
+			required[match.Variable] = true
+		case env.RuleAllowExtra:
+			findings[match.Variable] = append(findings[match.Variable], Finding{Rule: "extra", Level: LevelError, Message: "variable is not in the schema"}.at(positions[match.Variable]))
+		}
+	}
+
+	for _, name := range diff.Missing {
+		if required[name] {
+			findings[name] = append(findings[name], Finding{Rule: "missing", Level: LevelError, Message: "required variable is missing"})
+			continue
+		}
+
+		findings[name] = append(findings[name], Finding{Rule: "missing-diff", Level: LevelWarning, Message: "missing in " + target + ", set in " + source})
+	}
+
+	for _, name := range diff.Extra {
+		findings[name] = append(findings[name], Finding{Rule: "extra-diff", Level: LevelWarning, Message: "not in " + source}.at(positions[name]))
+	}
+
+	for name := range diff.Different {
+		variables = append(variables, name)
+		findings[name] = append(findings[name], Finding{Rule: "different", Level: LevelWarning, Message: "value differs from " + source}.at(positions[name]))
+	}
+
+	return newReport("diff "+source+" "+target, target, variables, findings)
+}
+
+// MatrixReport turns a comparison of several files into a report on each of
+// them. files names the environments of matrix in order, and positions
+// locates the variables in each file.
+func MatrixReport(files []string, matrix env.MatrixResult, positions []env.Positions) []Report {
+	reports := make([]Report, len(files))
+
+	for i, file := range files {
+		findings := make(map[string][]Finding)
+		var variables []string
+
+		for _, row := range matrix.Rows {
+			cell := row.Cells[i]
+			var others []string
+
+			if !cell.Present {
+				for j, other := range row.Cells {
+					if other.Present {
+						others = append(others, files[j])
+					}
+				}
+
+				findings[row.Key] = []Finding{{Rule: "missing-env", Level: LevelWarning, Message: "missing in " + file + ", set in " + strings.Join(others, ", ")}}
+				continue
+			}
+
+			variables = append(variables, row.Key)
+
+			for j, other := range row.Cells {
+				if other.Present && other.Group != cell.Group {
+					others = append(others, files[j])
+				}
+			}
+
+			if len(others) > 0 {
+				findings[row.Key] = []Finding{Finding{Rule: "different-env", Level: LevelWarning, Message: "value differs from " + strings.Join(others, ", ")}.at(positions[i][row.Key])}
+			}
+		}
+
+		reports[i] = newReport("diff "+file, file, variables, findings)
+	}
+
+	return reports
+}
+
+// LintReport turns the findings of the linter on file into a report with a
+// check for every name in variables. Findings that are not about a variable,
+// such as invalid lines, are checks of their line.
+func LintReport(file string, variables []string, findings []lint.Finding) Report {
+	levels := map[string]string{lint.SeverityError: LevelError, lint.SeverityWarn: LevelWarning}
+	grouped := make(map[string][]Finding)
+
+	for _, finding := range findings {
+		name := finding.Variable
+		if name == "" {
+			name = fmt.Sprintf("line %d", finding.Position.Line)
+		}
+
+		grouped[name] = append(grouped[name], Finding{
+			Rule:    finding.Rule,
+			Level:   levels[finding.Severity],
+			Message: finding.Message,
+		}.at(finding.Position))
+	}
+
+	return newReport("lint "+file, file, variables, grouped)
+}
+
+// SyncReport turns the decisions of a sync into notes on the target file.
+// positions locates the variables in the target.
+func SyncReport(result env.SyncResult, positions env.Positions) Report {
+	findings := make(map[string][]Finding)
+	variables := make([]string, 0, len(result.Decisions))
+
+	for _, decision := range result.Decisions {
+		variables = append(variables, decision.Variable)
+		findings[decision.Variable] = append(findings[decision.Variable], Finding{
+			Rule:    "sync",
+			Level:   LevelNote,
+			Message: decision.Action + ": " + decision.Reason,
+		}.at(positions[decision.Variable]))
+	}
+
+	return newReport("sync "+result.FilePath, result.FilePath, variables, findings)
+}
+
+func newReport(name, file string, variables []string, findings map[string][]Finding) Report {
+	report := Report{Name: name, File: file}
+
+	names := slices.Clone(variables)
+	for name := range findings {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	for _, name := range slices.Compact(names) {
+		report.Checks = append(report.Checks, Check{Variable: name, Findings: findings[name]})
+	}
+
+	return report
+}
+
+// failed reports whether the check has an error.
+func (c Check) failed() bool {
+	return slices.ContainsFunc(c.Findings, func(f Finding) bool { return f.Level == LevelError })
+}
+
+// WriteReports renders reports in one of the CI formats.
+func WriteReports(w io.Writer, format string, reports ...Report) error {
+	switch format {
+	case FormatSARIF:
+		return writeSARIF(w, reports)
+	case FormatJUnit:
+		return writeJUnit(w, reports)
+	case FormatGitHub:
+		writeGitHub(w, reports)
+	case FormatTAP:
+		writeTAP(w, reports)
+	case FormatMarkdown:
+		writeMarkdown(w, reports)
+	default:
+		return fmt.Errorf("unsupported report format %q", format)
+	}
+
+	return nil
+}
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+func writeSARIF(w io.Writer, reports []Report) error {
+	type region struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn,omitempty"`
+	}
+
+	type location struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+			Region *region `json:"region,omitempty"`
+		} `json:"physicalLocation"`
+	}
+
+	type result struct {
+		RuleID  string `json:"ruleId"`
+		Level   string `json:"level"`
+		Message struct {
+			Text string `json:"text"`
+		} `json:"message"`
+		Locations []location `json:"locations"`
+	}
+
+	type rule struct {
+		ID               string `json:"id"`
+		ShortDescription struct {
+			Text string `json:"text"`
+		} `json:"shortDescription"`
+	}
+
+	results := []result{}
+	used := make(map[string]bool)
+
+	for _, report := range reports {
+		for _, check := range report.Checks {
+			for _, finding := range check.Findings {
+				r := result{RuleID: finding.Rule, Level: finding.Level}
+				r.Message.Text = check.Variable + ": " + finding.Message
+
+				var loc location
+				loc.PhysicalLocation.ArtifactLocation.URI = report.File
+
+				if finding.Line > 0 {
+					loc.PhysicalLocation.Region = &region{finding.Line, finding.Column}
+				}
+
+				r.Locations = []location{loc}
+				results = append(results, r)
+				used[finding.Rule] = true
+			}
+		}
+	}
+
+	rules := []rule{}
+	for _, id := range slices.Sorted(maps.Keys(used)) {
+		r := rule{ID: id}
+		r.ShortDescription.Text = ruleDescription(id)
+		rules = append(rules, r)
+	}
+
+	document := map[string]any{
+		"version": "2.1.0",
+		"$schema": sarifSchema,
+		"runs": []any{map[string]any{
+			"tool": map[string]any{"driver": map[string]any{
+				"name":           "envsync",
+				"informationUri": "https://github.com/tommyalmeida/envsync",
+				"rules":          rules,
+			}},
+			"results": results,
+		}},
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+
+	return encoder.Encode(document)
+}
+
+func ruleDescription(id string) string {
+	if description, exists := Rules[id]; exists {
+		return description
+	}
+
+	for _, rule := range lint.Rules {
+		if rule.Name == id {
+			return rule.Description
+		}
+	}
+
+	return ""
+}
+
+func writeJUnit(w io.Writer, reports []Report) error {
+	type failure struct {
+		Message string `xml:"message,attr"`
+		Type    string `xml:"type,attr"`
+		Text    string `xml:",chardata"`
+	}
+
+	type testCase struct {
+		Name      string   `xml:"name,attr"`
+		ClassName string   `xml:"classname,attr"`
+		Failure   *failure `xml:"failure,omitempty"`
+		SystemOut string   `xml:"system-out,omitempty"`
+	}
+
+	type testSuite struct {
+		Name     string     `xml:"name,attr"`
+		Tests    int        `xml:"tests,attr"`
+		Failures int        `xml:"failures,attr"`
+		Cases    []testCase `xml:"testcase"`
+	}
+
+	suites := struct {
+		XMLName xml.Name    `xml:"testsuites"`
+		Suites  []testSuite `xml:"testsuite"`
+	}{}
+
+	for _, report := range reports {
+		suite := testSuite{Name: report.Name, Tests: len(report.Checks)}
+
+		for _, check := range report.Checks {
+			tc := testCase{Name: check.Variable, ClassName: report.File}
+
+			var errors, notes []string
+			for _, finding := range check.Findings {
+				text := finding.Level + ": " + finding.Message + location(report.File, finding)
+
+				if finding.Level == LevelError {
+					errors = append(errors, text)
+				} else {
+					notes = append(notes, text)
+				}
+			}
+
+			if i := slices.IndexFunc(check.Findings, func(f Finding) bool { return f.Level == LevelError }); i >= 0 {
+				suite.Failures++
+				tc.Failure = &failure{
+					Message: check.Findings[i].Message,
+					Type:    check.Findings[i].Rule,
+					Text:    strings.Join(errors, "\n"),
+				}
+			}
+
+			tc.SystemOut = strings.Join(notes, "\n")
+			suite.Cases = append(suite.Cases, tc)
+		}
+
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func location(file string, finding Finding) string {
+	if finding.Line == 0 {
+		return " (" + file + ")"
+	}
+
+	return " (" + schema.Position{File: file, Line: finding.Line, Column: finding.Column}.String() + ")"
+}
+
+// writeGitHub writes workflow commands that GitHub Actions shows as
+// annotations on the file.
+func writeGitHub(w io.Writer, reports []Report) {
+	commands := map[string]string{LevelError: "error", LevelWarning: "warning", LevelNote: "notice"}
+
+	for _, report := range reports {
+		for _, check := range report.Checks {
+			for _, finding := range check.Findings {
+				properties := "file=" + escapeProperty(report.File)
+
+				if finding.Line > 0 {
+					properties += fmt.Sprintf(",line=%d", finding.Line)
+				}
+
+				if finding.Column > 0 {
+					properties += fmt.Sprintf(",col=%d", finding.Column)
+				}
+
+				properties += ",title=" + escapeProperty("envsync "+finding.Rule)
+
+				fmt.Fprintf(w, "::%s %s::%s\n", commands[finding.Level], properties, escapeData(check.Variable+": "+finding.Message))
+			}
+		}
+	}
+}
+
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// writeTAP writes version 13 of the Test Anything Protocol, with one test
+// point per check.
+func writeTAP(w io.Writer, reports []Report) {
+	total := 0
+	for _, report := range reports {
+		total += len(report.Checks)
+	}
+
+	fmt.Fprintln(w, "TAP version 13")
+	fmt.Fprintf(w, "1..%d\n", total)
+
+	n := 0
+	for _, report := range reports {
+		fmt.Fprintf(w, "# %s\n", report.Name)
+
+		for _, check := range report.Checks {
+			n++
+
+			status := "ok"
+			if check.failed() {
+				status = "not ok"
+			}
+
+			fmt.Fprintf(w, "%s %d - %s\n", status, n, check.Variable)
+
+			for _, finding := range check.Findings {
+				fmt.Fprintf(w, "  # %s: %s%s\n", finding.Level, finding.Message, location(report.File, finding))
+			}
+		}
+	}
+}
+
+// writeMarkdown writes a summary suited to pull request comments and job
+// summaries.
+func writeMarkdown(w io.Writer, reports []Report) {
+	symbols := map[string]string{LevelError: "❌", LevelWarning: "⚠️", LevelNote: "ℹ️"}
+
+	for i, report := range reports {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+
+		fmt.Fprintf(w, "### envsync %s\n\n", report.Name)
+
+		counts := make(map[string]int)
+		var rows []string
+
+		for _, check := range report.Checks {
+			for _, finding := range check.Findings {
+				counts[finding.Level]++
+
+				line := ""
+				if finding.Line > 0 {
+					line = fmt.Sprint(finding.Line)
+				}
+
+				rows = append(rows, fmt.Sprintf("| %s | `%s` | %s | %s |",
+					symbols[finding.Level], check.Variable, line, escapeMarkdown(finding.Message)))
+			}
+		}
+
+		if len(rows) == 0 {
+			fmt.Fprintf(w, "✅ All %d checks passed.\n", len(report.Checks))
+			continue
+		}
+
+		fmt.Fprintf(w, "%d errors, %d warnings, %d notes in %d checks.\n\n",
+			counts[LevelError], counts[LevelWarning], counts[LevelNote], len(report.Checks))
+		fmt.Fprintln(w, "| | Variable | Line | Message |")
+		fmt.Fprintln(w, "|---|---|---|---|")
+
+		for _, row := range rows {
+			fmt.Fprintln(w, row)
+		}
+	}
+}
+
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
@@ -0,0 +1,310 @@
+package output_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tommyalmeida/envsync/internal/env"
+	"github.com/tommyalmeida/envsync/internal/lint"
+	"github.com/tommyalmeida/envsync/internal/output"
+	"github.com/tommyalmeida/envsync/pkg/schema"
+)
+
+func validationReport() output.Report {
+	result := env.ValidationResult{
+		Missing: []string{"DATABASE_URL"},
+		Errors:  []schema.ValidationError{{Variable: "PORT", Message: "not a valid port"}},
+		Extra:   []string{"LEGACY"},
+	}
+
+	return output.ValidationReport(".env", result,
+		[]string{"DATABASE_URL", "PORT", "LOG_LEVEL", "LEGACY"},
+		env.Positions{"PORT": {Line: 3, Column: 1}, "LOG_LEVEL": {Line: 1, Column: 1}, "LEGACY": {Line: 7, Column: 8}})
+}
+
+func TestValidationReport(t *testing.T) {
+	expected := output.Report{
+		Name: "validate .env",
+		File: ".env",
+		Checks: []output.Check{
+			{Variable: "DATABASE_URL", Findings: []output.Finding{{Rule: "missing", Level: output.LevelError, Message: "required variable is missing"}}},
+			{Variable: "LEGACY", Findings: []output.Finding{{Rule: "extra", Level: output.LevelWarning, Message: "variable is not in the schema", Line: 7, Column: 8}}},
+			{Variable: "LOG_LEVEL"},
+			{Variable: "PORT", Findings: []output.Finding{{Rule: "invalid", Level: output.LevelError, Message: "not a valid port", Line: 3, Column: 1}}},
+		},
+	}
+
+	if got := validationReport(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestValidationReport_DisallowedExtra(t *testing.T) {
+	result := env.ValidationResult{
+		Extra: []string{"LEGACY"},
+		Rules: []env.RuleMatch{{Variable: "LEGACY", Rule: env.RuleAllowExtra}},
+	}
+
+	report := output.ValidationReport(".env", result, nil, nil)
+
+	if level := report.Checks[0].Findings[0].Level; level != output.LevelError {
+		t.Errorf("expected an error when extra variables are not allowed, got %s", level)
+	}
+}
+
+func TestDiffReport(t *testing.T) {
+	diff := env.DiffResult{
+		Missing:   []string{"A", "E"},
+		Extra:     []string{"B"},
+		Different: map[string]env.Diff{"C": {Source: "1", Target: "2"}},
+		Same:      []string{"D"},
+		Rules: []env.RuleMatch{
+			{Variable: "B", Rule: env.RuleAllowExtra},
+			{Variable: "E", Rule: env.RuleRequireAll},
+		},
+	}
+
+	report := output.DiffReport(".env.example", ".env", diff, env.Positions{"B": {Line: 2}, "C": {Line: 4}})
+
+	var got []string
+	for _, check := range report.Checks {
+		line := check.Variable
+		for _, finding := range check.Findings {
+			line += " " + finding.Rule + ":" + finding.Level
+		}
+		got = append(got, line)
+	}
+
+	expected := []string{"A missing-diff:warning", "B extra:error extra-diff:warning", "C different:warning", "D", "E missing:error"}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestMatrixReport(t *testing.T) {
+	matrix := env.CompareMany([]string{"dev", "staging", "prod"}, []env.Vars{
+		{"A": "1", "B": "x", "C": "same"},
+		{"A": "1", "B": "y", "C": "same"},
+		{"B": "x", "C": "same"},
+	})
+
+	positions := []env.Positions{nil, {"B": {Line: 2, Column: 1}}, nil}
+	reports := output.MatrixReport([]string{".env.dev", ".env.staging", ".env.prod"}, matrix, positions)
+
+	var got []string
+	for _, report := range reports {
+		for _, check := range report.Checks {
+			line := report.File + " " + check.Variable
+			for _, finding := range check.Findings {
+				line += fmt.Sprintf(" %s@%d: %s", finding.Rule, finding.Line, finding.Message)
+			}
+			got = append(got, line)
+		}
+	}
+
+	expected := []string{
+		".env.dev A",
+		".env.dev B different-env@0: value differs from .env.staging",
+		".env.dev C",
+		".env.staging A",
+		".env.staging B different-env@2: value differs from .env.dev, .env.prod",
+		".env.staging C",
+		".env.prod A missing-env@0: missing in .env.prod, set in .env.dev, .env.staging",
+		".env.prod B different-env@0: value differs from .env.staging",
+		".env.prod C",
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestLintReport(t *testing.T) {
+	findings := []lint.Finding{
+		{Rule: "duplicate-key", Severity: lint.SeverityError, Message: "A is assigned again", Variable: "A", Position: schema.Position{Line: 1, Column: 1}},
+		{Rule: "invalid-line", Severity: lint.SeverityError, Message: "cannot parse", Position: schema.Position{Line: 3, Column: 1}},
+		{Rule: "key-case", Severity: lint.SeverityWarn, Message: "b is not upper case", Variable: "b", Position: schema.Position{Line: 4, Column: 1}},
+	}
+
+	report := output.LintReport(".env", []string{"A", "B", "b"}, findings)
+
+	var got []string
+	for _, check := range report.Checks {
+		line := check.Variable
+		for _, finding := range check.Findings {
+			line += fmt.Sprintf(" %s %s@%d: %s", finding.Level, finding.Rule, finding.Line, finding.Message)
+		}
+		got = append(got, line)
+	}
+
+	expected := []string{
+		"A error duplicate-key@1: A is assigned again",
+		"B",
+		"b warning key-case@4: b is not upper case",
+		"line 3 error invalid-line@3: cannot parse",
+	}
+
+	if report.Name != "lint .env" {
+		t.Errorf("expected name %q, got %q", "lint .env", report.Name)
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestWriteReports_SARIF(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := output.WriteReports(&buf, output.FormatSARIF, validationReport()); err != nil {
+		t.Fatalf("WriteReports() error = %v", err)
+	}
+
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 3 {
+		t.Fatalf("unexpected SARIF log:\n%s", buf.String())
+	}
+
+	port := log.Runs[0].Results[2]
+	location := port.Locations[0].PhysicalLocation
+
+	if port.RuleID != "invalid" || port.Level != "error" || location.ArtifactLocation.URI != ".env" || location.Region.StartLine != 3 {
+		t.Errorf("unexpected result for PORT: %+v", port)
+	}
+}
+
+func TestWriteReports_JUnit(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := output.WriteReports(&buf, output.FormatJUnit, validationReport()); err != nil {
+		t.Fatalf("WriteReports() error = %v", err)
+	}
+
+	var suites struct {
+		Suites []struct {
+			Name     string `xml:"name,attr"`
+			Tests    int    `xml:"tests,attr"`
+			Failures int    `xml:"failures,attr"`
+		} `xml:"testsuite"`
+	}
+
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("invalid XML: %v", err)
+	}
+
+	if len(suites.Suites) != 1 || suites.Suites[0].Tests != 4 || suites.Suites[0].Failures != 2 {
+		t.Errorf("unexpected JUnit report:\n%s", buf.String())
+	}
+}
+
+func TestWriteReports_JUnitFailureFromError(t *testing.T) {
+	var buf bytes.Buffer
+
+	report := output.Report{Name: "lint .env", File: ".env", Checks: []output.Check{{
+		Variable: "A",
+		Findings: []output.Finding{
+			{Rule: "key-case", Level: output.LevelWarning, Message: "not upper case"},
+			{Rule: "duplicate-key", Level: output.LevelError, Message: "assigned again"},
+		},
+	}}}
+
+	if err := output.WriteReports(&buf, output.FormatJUnit, report); err != nil {
+		t.Fatalf("WriteReports() error = %v", err)
+	}
+
+	if !strings.Contains(buf.String(), `<failure message="assigned again" type="duplicate-key">`) {
+		t.Errorf("expected the failure to describe the error, got:\n%s", buf.String())
+	}
+}
+
+func TestWriteReports_GitHub(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := output.WriteReports(&buf, output.FormatGitHub, validationReport()); err != nil {
+		t.Fatalf("WriteReports() error = %v", err)
+	}
+
+	expected := `::error file=.env,title=envsync missing::DATABASE_URL: required variable is missing
+::warning file=.env,line=7,col=8,title=envsync extra::LEGACY: variable is not in the schema
+::error file=.env,line=3,col=1,title=envsync invalid::PORT: not a valid port
+`
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestWriteReports_TAP(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := output.WriteReports(&buf, output.FormatTAP, validationReport()); err != nil {
+		t.Fatalf("WriteReports() error = %v", err)
+	}
+
+	expected := `TAP version 13
+1..4
+# validate .env
+not ok 1 - DATABASE_URL
+  # error: required variable is missing (.env)
+ok 2 - LEGACY
+  # warning: variable is not in the schema (.env:7:8)
+ok 3 - LOG_LEVEL
+not ok 4 - PORT
+  # error: not a valid port (.env:3:1)
+`
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestWriteReports_Markdown(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := output.WriteReports(&buf, output.FormatMarkdown, validationReport()); err != nil {
+		t.Fatalf("WriteReports() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"### envsync validate .env",
+		"2 errors, 1 warnings, 0 notes in 4 checks.",
+		"| ❌ | `PORT` | 3 | not a valid port |",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestWriteReports_UnknownFormat(t *testing.T) {
+	if err := output.WriteReports(&bytes.Buffer{}, "xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -1,13 +1,20 @@
 package main
 
 import (
+	"errors"
 	"log"
+	"os"
 
 	"github.com/tommyalmeida/envsync/cmd"
 )
 
 func main() {
 	if err := cmd.Execute(); err != nil {
+		var exitErr *cmd.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
+
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,98 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidateConstraints checks the rules that relate variables to each other:
+// requires, conflicts_with, required_if and mutually_exclusive groups. A
+// variable counts as set when it has a non-empty value.
+func (s Schema) ValidateConstraints(vars map[string]string) []ValidationError {
+	var errors []ValidationError
+
+	isSet := func(name string) bool {
+		return vars[name] != ""
+	}
+
+	// A conflict declared on both variables is reported once.
+	conflicts := make(map[[2]string]bool)
+
+	for _, name := range s.variableNames() {
+		variable := s.Variables[name]
+
+		if isSet(name) {
+			for _, required := range variable.Requires {
+				if !isSet(required) {
+					errors = append(errors, ValidationError{
+						Variable: name,
+						Message:  fmt.Sprintf("requires %s, which is not set", required),
+					})
+				}
+			}
+
+			for _, conflict := range variable.ConflictsWith {
+				pair := [2]string{min(name, conflict), max(name, conflict)}
+
+				if isSet(conflict) && !conflicts[pair] {
+					conflicts[pair] = true
+					errors = append(errors, ValidationError{
+						Variable: name,
+						Message:  fmt.Sprintf("conflicts with %s, only one of them may be set", conflict),
+					})
+				}
+			}
+
+			continue
+		}
+
+		conditions := make([]string, 0, len(variable.RequiredIf))
+		for condition := range variable.RequiredIf {
+			conditions = append(conditions, condition)
+		}
+		sort.Strings(conditions)
+
+		for _, condition := range conditions {
+			if value, exists := vars[condition]; exists && value == variable.RequiredIf[condition] {
+				errors = append(errors, ValidationError{
+					Variable: name,
+					Message:  fmt.Sprintf("required because %s is %q", condition, value),
+				})
+				break
+			}
+		}
+	}
+
+	for _, group := range s.MutuallyExclusive {
+		var set []string
+
+		for _, name := range group {
+			if isSet(name) {
+				set = append(set, name)
+			}
+		}
+
+		if len(set) > 1 {
+			errors = append(errors, ValidationError{
+				Variable: set[0],
+				Message: fmt.Sprintf("only one of %s may be set, but %s are set",
+					strings.Join(group, ", "), strings.Join(set, " and ")),
+			})
+		}
+	}
+
+	return errors
+}
+
+func (s Schema) variableNames() []string {
+	names := make([]string, 0, len(s.Variables))
+
+	for name := range s.Variables {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,661 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// typePatterns are the JSON Schema patterns of types that have no standard
+// format. Env values are always strings, so every type is a string schema.
+var typePatterns = map[string]string{
+	"number":   `^\d+(\.\d+)?$`,
+	"integer":  `^[-+]?\d+$`,
+	"port":     `^\d+$`,
+	"duration": `^[-+]?(0|((\d+(\.\d*)?|\.\d+)(ns|us|µs|ms|s|m|h))+)$`,
+	"cidr":     `^[0-9A-Fa-f.:]+/\d+$`,
+	"semver":   semverPattern.String(),
+}
+
+var typeFormats = map[string]string{
+	"hostname": "hostname",
+	"uuid":     "uuid",
+	"url":      "uri",
+	"email":    "email",
+}
+
+// envsyncExtension holds what JSON Schema cannot express, so that an
+// exported schema imports without loss. Rules are only set at the top level.
+type envsyncExtension struct {
+	Type    string   `json:"type,omitempty"`
+	Min     *float64 `json:"min,omitempty"`
+	Max     *float64 `json:"max,omitempty"`
+	Schemes []string `json:"schemes,omitempty"`
+	Rules   []Rule   `json:"rules,omitempty"`
+}
+
+// ToJSONSchema converts s into a JSON Schema draft 2020-12 document for an
+// object of string values. allowExtra controls additionalProperties.
+func ToJSONSchema(s Schema, allowExtra bool) ([]byte, error) {
+	properties := make(map[string]any, len(s.Variables))
+	required := []string{}
+
+	for name, variable := range s.Variables {
+		properties[name] = variableJSONSchema(variable)
+
+		if variable.Required {
+			required = append(required, name)
+		}
+	}
+
+	sort.Strings(required)
+
+	var additional any = false
+	if allowExtra {
+		additional = map[string]any{"type": "string"}
+	}
+
+	document := map[string]any{
+		"$schema":              JSONSchemaDraft,
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": additional,
+	}
+
+	addConstraints(document, s)
+
+	if len(s.Rules) > 0 {
+		document["x-envsync"] = envsyncExtension{Rules: s.Rules}
+	}
+
+	return json.MarshalIndent(document, "", "  ")
+}
+
+// addConstraints expresses the cross-variable constraints with
+// dependentRequired, dependentSchemas and allOf. JSON Schema counts a
+// variable as set when it is present, even when it is empty.
+func addConstraints(document map[string]any, s Schema) {
+	dependentRequired := make(map[string]any)
+	dependentSchemas := make(map[string]any)
+	var allOf []any
+
+	for _, name := range s.variableNames() {
+		variable := s.Variables[name]
+
+		if len(variable.Requires) > 0 {
+			dependentRequired[name] = variable.Requires
+		}
+
+		if len(variable.ConflictsWith) > 0 {
+			dependentSchemas[name] = map[string]any{"not": anyRequired(variable.ConflictsWith)}
+		}
+
+		conditions := make([]string, 0, len(variable.RequiredIf))
+		for condition := range variable.RequiredIf {
+			conditions = append(conditions, condition)
+		}
+		sort.Strings(conditions)
+
+		for _, condition := range conditions {
+			allOf = append(allOf, map[string]any{
+				"if": map[string]any{
+					"properties": map[string]any{condition: map[string]any{"const": variable.RequiredIf[condition]}},
+					"required":   []string{condition},
+				},
+				"then": map[string]any{"required": []string{name}},
+			})
+		}
+	}
+
+	for _, group := range s.MutuallyExclusive {
+		// A group of one excludes nothing, and an empty anyOf is invalid.
+		if len(group) < 2 {
+			continue
+		}
+
+		var pairs []any
+
+		for i, a := range group {
+			for _, b := range group[i+1:] {
+				pairs = append(pairs, map[string]any{"required": []string{a, b}})
+			}
+		}
+
+		allOf = append(allOf, map[string]any{"not": map[string]any{"anyOf": pairs}})
+	}
+
+	if len(dependentRequired) > 0 {
+		document["dependentRequired"] = dependentRequired
+	}
+
+	if len(dependentSchemas) > 0 {
+		document["dependentSchemas"] = dependentSchemas
+	}
+
+	if len(allOf) > 0 {
+		document["allOf"] = allOf
+	}
+}
+
+func anyRequired(names []string) map[string]any {
+	if len(names) == 1 {
+		return map[string]any{"required": names}
+	}
+
+	anyOf := make([]any, len(names))
+	for i, name := range names {
+		anyOf[i] = map[string]any{"required": []string{name}}
+	}
+
+	return map[string]any{"anyOf": anyOf}
+}
+
+func variableJSONSchema(variable Variable) map[string]any {
+	property := map[string]any{"type": "string"}
+
+	if variable.Description != "" {
+		property["description"] = variable.Description
+	}
+
+	// Secret defaults stay out of a document meant to be shared with tools.
+	if variable.Default != "" && !variable.Secret {
+		property["default"] = variable.Default
+	}
+
+	if variable.Secret {
+		property["writeOnly"] = true
+	}
+
+	// JSON Schema only requires the key to be present, envsync also requires
+	// a value.
+	if variable.Required {
+		property["minLength"] = 1
+	}
+
+	var patterns []string
+
+	if pattern, exists := typePatterns[variable.Type]; exists {
+		patterns = append(patterns, pattern)
+	}
+
+	if variable.Pattern != "" {
+		patterns = append(patterns, variable.Pattern)
+	}
+
+	switch len(patterns) {
+	case 1:
+		property["pattern"] = patterns[0]
+	case 2:
+		property["allOf"] = []any{
+			map[string]any{"pattern": patterns[0]},
+			map[string]any{"pattern": patterns[1]},
+		}
+	}
+
+	if format, exists := typeFormats[variable.Type]; exists {
+		property["format"] = format
+	}
+
+	switch variable.Type {
+	case "enum":
+		property["enum"] = variable.Values
+	case "boolean":
+		property["enum"] = []string{"true", "false", "1", "0", "yes", "no", "on", "off"}
+	case "ip":
+		property["anyOf"] = []any{
+			map[string]any{"format": "ipv4"},
+			map[string]any{"format": "ipv6"},
+		}
+	case "json":
+		property["contentMediaType"] = "application/json"
+	case "base64":
+		property["contentEncoding"] = "base64"
+	}
+
+	if variable.Type != "" && variable.Type != "string" {
+		property["x-envsync"] = envsyncExtension{
+			Type:    variable.Type,
+			Min:     variable.Min,
+			Max:     variable.Max,
+			Schemes: variable.Schemes,
+		}
+	}
+
+	return property
+}
+
+// ignoredKeywords are annotations that carry no validation and are dropped
+// on import without a warning.
+var ignoredKeywords = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true, "examples": true,
+	"readOnly": true, "deprecated": true, "$defs": true, "definitions": true,
+}
+
+// FromJSONSchema converts a JSON Schema object into a schema. The conversion
+// is lossy: keywords that have no envsync equivalent are dropped and
+// reported as warnings. Schemas exported by ToJSONSchema convert back
+// without loss.
+func FromJSONSchema(data []byte) (Schema, []string, error) {
+	var root map[string]any
+
+	if err := json.Unmarshal(data, &root); err != nil {
+		return Schema{}, nil, fmt.Errorf("failed to parse JSON Schema: %w", err)
+	}
+
+	if t, exists := root["type"]; exists && t != "object" {
+		return Schema{}, nil, fmt.Errorf("JSON Schema must describe an object, not %v", t)
+	}
+
+	properties, _ := root["properties"].(map[string]any)
+	required, _ := root["required"].([]any)
+	s := Schema{Variables: make(map[string]Variable, len(properties))}
+	var warnings []string
+
+	warn := func(format string, args ...any) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+
+	for key := range root {
+		switch key {
+		case "type", "properties", "required", "dependentRequired", "dependentSchemas", "allOf":
+		case "x-envsync":
+			encoded, _ := json.Marshal(root[key])
+
+			var ext envsyncExtension
+			if err := json.Unmarshal(encoded, &ext); err != nil {
+				warn("invalid x-envsync at the top level: %v", err)
+			}
+
+			s.Rules = ext.Rules
+		case "additionalProperties":
+			if root[key] == false {
+				warn("additionalProperties: false is not imported, set rules.allow_extra: false instead")
+			}
+		default:
+			if !ignoredKeywords[key] {
+				warn("unsupported keyword %s at the top level", key)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property, ok := properties[name].(map[string]any)
+
+		if !ok {
+			warn("%s: property schema is not an object, imported as a string", name)
+			s.Variables[name] = Variable{}
+			continue
+		}
+
+		s.Variables[name] = importVariable(property, slices.Contains(required, any(name)), func(format string, args ...any) {
+			warn(name+": "+format, args...)
+		})
+	}
+
+	for _, item := range required {
+		name, _ := item.(string)
+		variable, exists := s.Variables[name]
+
+		if !exists {
+			warn("required variable %v has no property, imported as a string", item)
+		}
+
+		variable.Required = true
+		s.Variables[name] = variable
+	}
+
+	importConstraints(root, &s, warn)
+
+	return s, warnings, nil
+}
+
+// importConstraints reads back the shapes addConstraints writes and warns
+// about anything else in dependentRequired, dependentSchemas and allOf.
+func importConstraints(root map[string]any, s *Schema, warn func(format string, args ...any)) {
+	update := func(name string, apply func(*Variable)) {
+		variable := s.Variables[name]
+		apply(&variable)
+		s.Variables[name] = variable
+	}
+
+	dependentRequired, _ := root["dependentRequired"].(map[string]any)
+	for _, name := range sortedNames(dependentRequired) {
+		update(name, func(v *Variable) { v.Requires = stringValues(dependentRequired[name]) })
+	}
+
+	dependentSchemas, _ := root["dependentSchemas"].(map[string]any)
+	for _, name := range sortedNames(dependentSchemas) {
+		sub, _ := dependentSchemas[name].(map[string]any)
+		conflicts, ok := requiredAlternatives(sub["not"])
+
+		if !ok || len(sub) != 1 {
+			warn("%s: unsupported dependentSchemas entry", name)
+			continue
+		}
+
+		update(name, func(v *Variable) { v.ConflictsWith = conflicts })
+	}
+
+	allOf, _ := root["allOf"].([]any)
+	for i, item := range allOf {
+		sub, _ := item.(map[string]any)
+
+		if condition, value, target, ok := requiredIfShape(sub); ok {
+			update(target, func(v *Variable) {
+				if v.RequiredIf == nil {
+					v.RequiredIf = make(map[string]string)
+				}
+				v.RequiredIf[condition] = value
+			})
+			continue
+		}
+
+		if group, ok := exclusiveShape(sub); ok {
+			s.MutuallyExclusive = append(s.MutuallyExclusive, group)
+			continue
+		}
+
+		warn("unsupported allOf entry %d", i)
+	}
+}
+
+// requiredAlternatives parses {"required": [A]} or an anyOf of them.
+func requiredAlternatives(v any) ([]string, bool) {
+	sub, _ := v.(map[string]any)
+
+	if required, ok := sub["required"]; ok && len(sub) == 1 {
+		return stringValues(required), true
+	}
+
+	anyOf, ok := sub["anyOf"].([]any)
+	if !ok || len(sub) != 1 {
+		return nil, false
+	}
+
+	var names []string
+
+	for _, item := range anyOf {
+		alternative, ok := requiredAlternatives(item)
+
+		if !ok || len(alternative) != 1 {
+			return nil, false
+		}
+
+		names = append(names, alternative[0])
+	}
+
+	return names, true
+}
+
+func requiredIfShape(sub map[string]any) (string, string, string, bool) {
+	condition, _ := sub["if"].(map[string]any)
+	then, _ := sub["then"].(map[string]any)
+
+	properties, _ := condition["properties"].(map[string]any)
+	targets := stringValues(then["required"])
+
+	if len(sub) != 2 || len(properties) != 1 || len(targets) != 1 {
+		return "", "", "", false
+	}
+
+	for name, property := range properties {
+		constant, ok := property.(map[string]any)["const"]
+
+		if !ok {
+			return "", "", "", false
+		}
+
+		return name, scalarString(constant), targets[0], true
+	}
+
+	return "", "", "", false
+}
+
+// exclusiveShape parses {"not": {"anyOf": [{"required": [A, B]}, ...]}}
+// back into the group of every name in the pairs, in order of appearance.
+func exclusiveShape(sub map[string]any) ([]string, bool) {
+	not, _ := sub["not"].(map[string]any)
+	pairs, ok := not["anyOf"].([]any)
+
+	if !ok || len(sub) != 1 || len(not) != 1 {
+		return nil, false
+	}
+
+	var group []string
+	seen := make(map[string]bool)
+
+	for _, pair := range pairs {
+		required, _ := pair.(map[string]any)
+		names := stringValues(required["required"])
+
+		if len(names) != 2 || len(required) != 1 {
+			return nil, false
+		}
+
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				group = append(group, name)
+			}
+		}
+	}
+
+	return group, len(group) > 0
+}
+
+func sortedNames(m map[string]any) []string {
+	names := make([]string, 0, len(m))
+
+	for name := range m {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// importVariable converts a property schema. required reports whether the
+// property is in the required list, where minLength: 1 is what ToJSONSchema
+// writes for a required variable.
+func importVariable(property map[string]any, required bool, warn func(format string, args ...any)) Variable {
+	var variable Variable
+	handled := map[string]bool{"type": true, "x-envsync": true}
+
+	if minLength, ok := property["minLength"].(float64); ok && minLength == 1 && required {
+		handled["minLength"] = true
+	}
+
+	if description, ok := property["description"].(string); ok {
+		variable.Description = description
+		handled["description"] = true
+	}
+
+	if value, exists := property["default"]; exists {
+		variable.Default = scalarString(value)
+		handled["default"] = true
+	}
+
+	if writeOnly, ok := property["writeOnly"].(bool); ok {
+		variable.Secret = writeOnly
+		handled["writeOnly"] = true
+	}
+
+	if extension, ok := property["x-envsync"].(map[string]any); ok {
+		encoded, _ := json.Marshal(extension)
+
+		var ext envsyncExtension
+		if err := json.Unmarshal(encoded, &ext); err != nil {
+			warn("invalid x-envsync: %v", err)
+		}
+
+		variable.Type = ext.Type
+		variable.Min = ext.Min
+		variable.Max = ext.Max
+		variable.Schemes = ext.Schemes
+
+		// The keywords ToJSONSchema derives from the type add nothing.
+		for _, key := range []string{"format", "anyOf", "contentMediaType", "contentEncoding"} {
+			handled[key] = true
+		}
+
+		if variable.Type == "enum" {
+			variable.Values = stringValues(property["enum"])
+		}
+
+		handled["enum"] = true
+		importPatterns(property, typePatterns[variable.Type], &variable, handled, warn)
+	} else {
+		importType(property, &variable, handled, warn)
+		importPatterns(property, "", &variable, handled, warn)
+	}
+
+	for key := range property {
+		if !handled[key] && !ignoredKeywords[key] {
+			warn("unsupported keyword %s", key)
+		}
+	}
+
+	return variable
+}
+
+// importType maps the type, format, enum and bounds of a plain JSON Schema.
+func importType(property map[string]any, variable *Variable, handled map[string]bool, warn func(format string, args ...any)) {
+	switch t := property["type"]; t {
+	case "integer", "number", "boolean":
+		variable.Type = t.(string)
+	case "object", "array":
+		variable.Type = "json"
+	case nil, "string":
+	default:
+		warn("unsupported type %v, imported as a string", t)
+	}
+
+	if values, exists := property["enum"]; exists {
+		variable.Type = "enum"
+		variable.Values = stringValues(values)
+		handled["enum"] = true
+	}
+
+	if format, ok := property["format"].(string); ok {
+		handled["format"] = true
+
+		switch format {
+		case "hostname", "idn-hostname":
+			variable.Type = "hostname"
+		case "uuid", "email":
+			variable.Type = format
+		case "uri", "iri", "url":
+			variable.Type = "url"
+		case "ipv4", "ipv6":
+			variable.Type = "ip"
+		case "duration":
+			warn("ISO 8601 durations are not supported, imported as a string")
+		default:
+			warn("unsupported format %s, imported as a string", format)
+		}
+	}
+
+	if contentType, ok := property["contentMediaType"].(string); ok && contentType == "application/json" {
+		variable.Type = "json"
+		handled["contentMediaType"] = true
+	}
+
+	if encoding, ok := property["contentEncoding"].(string); ok && encoding == "base64" {
+		variable.Type = "base64"
+		handled["contentEncoding"] = true
+	}
+
+	for key, target := range map[string]**float64{"minimum": &variable.Min, "maximum": &variable.Max} {
+		if n, ok := property[key].(float64); ok {
+			*target = &n
+			handled[key] = true
+		}
+	}
+
+	if (variable.Min != nil || variable.Max != nil) && variable.Type != "integer" && variable.Type != "number" {
+		warn("minimum and maximum only apply to integer and number types")
+	}
+}
+
+// importPatterns takes the user pattern from pattern or allOf, skipping
+// typePattern, which the type already implies.
+func importPatterns(property map[string]any, typePattern string, variable *Variable, handled map[string]bool, warn func(format string, args ...any)) {
+	var patterns []string
+
+	if pattern, ok := property["pattern"].(string); ok {
+		patterns = append(patterns, pattern)
+		handled["pattern"] = true
+	}
+
+	if allOf, ok := property["allOf"].([]any); ok {
+		onlyPatterns := true
+
+		for _, item := range allOf {
+			sub, _ := item.(map[string]any)
+			pattern, ok := sub["pattern"].(string)
+
+			if !ok || len(sub) != 1 {
+				onlyPatterns = false
+				continue
+			}
+
+			patterns = append(patterns, pattern)
+		}
+
+		handled["allOf"] = onlyPatterns
+	}
+
+	var user []string
+	for _, pattern := range patterns {
+		if pattern != typePattern {
+			user = append(user, pattern)
+		}
+	}
+
+	if len(user) > 1 {
+		warn("only one pattern is supported, keeping %s", user[len(user)-1])
+	}
+
+	if len(user) > 0 {
+		variable.Pattern = user[len(user)-1]
+	}
+}
+
+func stringValues(v any) []string {
+	items, _ := v.([]any)
+	values := make([]string, 0, len(items))
+
+	for _, item := range items {
+		values = append(values, scalarString(item))
+	}
+
+	return values
+}
+
+func scalarString(v any) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	case nil:
+		return ""
+	default:
+		encoded, _ := json.Marshal(value)
+		return string(encoded)
+	}
+}
@@ -0,0 +1,157 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tommyalmeida/envsync/pkg/schema"
+)
+
+func TestJSONSchema_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	minPort, maxPort := 1024.0, 65535.0
+
+	original := schema.Schema{
+		Variables: map[string]schema.Variable{
+			"PORT":      {Required: true, Type: "port", Default: "3000", Min: &minPort, Max: &maxPort, Description: "Server port"},
+			"LOG_LEVEL": {Type: "enum", Values: []string{"debug", "info"}},
+			"API_URL":   {Type: "url", Schemes: []string{"https"}, Pattern: `\.example\.com`},
+			"VERSION":   {Type: "semver"},
+			"NAME":      {Pattern: "^[a-z]+$"},
+			"TOKEN":     {Required: true, Secret: true},
+			"ENABLED":   {Type: "boolean"},
+			"BIND":      {Type: "ip"},
+			"SETTINGS":  {Type: "json"},
+			"TLS_CERT":  {Requires: []string{"TLS_KEY"}, RequiredIf: map[string]string{"ENABLED": "true"}},
+			"TLS_KEY":   {ConflictsWith: []string{"NAME", "BIND"}},
+		},
+		MutuallyExclusive: [][]string{{"API_URL", "BIND", "NAME"}},
+		Rules:             []schema.Rule{{Expr: "PORT != 8080", Message: "8080 is reserved", Variable: "PORT"}},
+	}
+
+	document, err := schema.ToJSONSchema(original, false)
+	if err != nil {
+		t.Fatalf("ToJSONSchema() error = %v", err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(document, &decoded); err != nil {
+		t.Fatalf("exported schema is not JSON: %v", err)
+	}
+
+	if decoded["$schema"] != schema.JSONSchemaDraft || decoded["additionalProperties"] != false {
+		t.Errorf("unexpected document header: %s", document)
+	}
+
+	if !reflect.DeepEqual(decoded["required"], []any{"PORT", "TOKEN"}) {
+		t.Errorf("required = %v, want [PORT TOKEN]", decoded["required"])
+	}
+
+	properties, _ := decoded["properties"].(map[string]any)
+	for name, want := range map[string]any{"PORT": 1.0, "TOKEN": 1.0, "NAME": nil} {
+		if got := properties[name].(map[string]any)["minLength"]; got != want {
+			t.Errorf("%s minLength = %v, want %v", name, got, want)
+		}
+	}
+
+	imported, warnings, err := schema.FromJSONSchema(document)
+	if err != nil {
+		t.Fatalf("FromJSONSchema() error = %v", err)
+	}
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "allow_extra") {
+		t.Errorf("expected only the additionalProperties warning, got %v", warnings)
+	}
+
+	if !reflect.DeepEqual(imported, original) {
+		t.Errorf("round trip changed the schema:\ngot  %+v\nwant %+v", imported, original)
+	}
+}
+
+func TestToJSONSchema_MutuallyExclusiveOfOne(t *testing.T) {
+	t.Parallel()
+
+	s := schema.Schema{
+		Variables:         map[string]schema.Variable{"A": {}},
+		MutuallyExclusive: [][]string{{"A"}, {}},
+	}
+
+	document, err := schema.ToJSONSchema(s, true)
+	if err != nil {
+		t.Fatalf("ToJSONSchema() error = %v", err)
+	}
+
+	if strings.Contains(string(document), "anyOf") || strings.Contains(string(document), "allOf") {
+		t.Errorf("groups of fewer than two variables should not be exported: %s", document)
+	}
+}
+
+func TestFromJSONSchema_Foreign(t *testing.T) {
+	t.Parallel()
+
+	document := `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title": "Service",
+		"type": "object",
+		"properties": {
+			"WORKERS": {"type": "integer", "minimum": 1, "maximum": 16, "default": 4},
+			"MODE": {"enum": ["fast", "safe"], "description": "Run mode"},
+			"CALLBACK": {"type": "string", "format": "uri"},
+			"CONTACT": {"type": "string", "format": "email"},
+			"STARTED": {"type": "string", "format": "date-time"},
+			"CODE": {"type": "string", "minLength": 4, "pattern": "^[A-Z]+$"},
+			"LABELS": {"type": "array"},
+			"OWNER": {"type": "string", "minLength": 1},
+			"TEAM": {"type": "string", "minLength": 1}
+		},
+		"required": ["WORKERS", "MISSING", "OWNER"],
+		"patternProperties": {}
+	}`
+
+	imported, warnings, err := schema.FromJSONSchema([]byte(document))
+	if err != nil {
+		t.Fatalf("FromJSONSchema() error = %v", err)
+	}
+
+	minWorkers, maxWorkers := 1.0, 16.0
+	expected := map[string]schema.Variable{
+		"WORKERS":  {Required: true, Type: "integer", Default: "4", Min: &minWorkers, Max: &maxWorkers},
+		"MODE":     {Type: "enum", Values: []string{"fast", "safe"}, Description: "Run mode"},
+		"CALLBACK": {Type: "url"},
+		"CONTACT":  {Type: "email"},
+		"STARTED":  {},
+		"CODE":     {Pattern: "^[A-Z]+$"},
+		"LABELS":   {Type: "json"},
+		"OWNER":    {Required: true},
+		"TEAM":     {},
+		"MISSING":  {Required: true},
+	}
+
+	if !reflect.DeepEqual(imported.Variables, expected) {
+		t.Errorf("got  %+v\nwant %+v", imported.Variables, expected)
+	}
+
+	for _, want := range []string{"patternProperties", "STARTED: unsupported format date-time", "CODE: unsupported keyword minLength", "TEAM: unsupported keyword minLength", "MISSING"} {
+		found := false
+		for _, warning := range warnings {
+			found = found || strings.Contains(warning, want)
+		}
+
+		if !found {
+			t.Errorf("expected a warning about %q, got %v", want, warnings)
+		}
+	}
+
+	for _, warning := range warnings {
+		if strings.HasPrefix(warning, "OWNER") {
+			t.Errorf("minLength: 1 on a required property should not warn, got %q", warning)
+		}
+	}
+
+	if _, _, err := schema.FromJSONSchema([]byte(`{"type": "array"}`)); err == nil {
+		t.Error("expected error for a non-object schema, got none")
+	}
+}
@@ -0,0 +1,230 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/vm"
+)
+
+// Rule is a boolean expression over the variables, written in the expr
+// language (https://expr-lang.org). Variables are typed by their schema
+// type: integers and ports are ints, numbers are floats, booleans are bools,
+// durations are time.Durations, json values are decoded and everything else
+// is a string. Variables outside the schema are strings.
+type Rule struct {
+	Expr string `yaml:"expr" json:"expr"`
+	// Message is reported when the expression is false.
+	Message string `yaml:"message,omitempty" json:"message,omitempty"`
+	// Variable is the variable failures are reported for. It defaults to the
+	// first variable the expression refers to.
+	Variable string `yaml:"variable,omitempty" json:"variable,omitempty"`
+
+	// program is the compiled expression, set by CompileRules.
+	program *vm.Program
+}
+
+// CompileRules compiles every rule against the schema types, so that syntax
+// and type errors are found before any env file is validated and rules are
+// not compiled again for every validation. Rules that do not compile are
+// left as they are and reported by ValidateRules; the first error is
+// returned. Compile again after changing the variable types.
+func (s *Schema) CompileRules() error {
+	var first error
+
+	// Copies of the schema share the rules, and their types can differ.
+	s.Rules = slices.Clone(s.Rules)
+
+	for i := range s.Rules {
+		rule := &s.Rules[i]
+		program, err := s.compileRule(*rule)
+
+		if err != nil && first == nil {
+			first = fmt.Errorf("rule %d (%s): %w", i+1, rule.Expr, err)
+		}
+
+		rule.program = program
+	}
+
+	return first
+}
+
+// CheckRule compiles a single rule against the schema types.
+func (s Schema) CheckRule(rule Rule) error {
+	_, err := s.compileRule(rule)
+	return err
+}
+
+// ValidateRules evaluates the rules against vars. A rule that refers to a
+// schema variable that is not set, or whose value is not valid for its type,
+// is skipped: those problems are reported by the other checks. Rules that
+// CompileRules has not compiled are compiled first.
+func (s Schema) ValidateRules(vars map[string]string) []ValidationError {
+	var errors []ValidationError
+
+	for _, rule := range s.Rules {
+		program := rule.program
+
+		var err error
+		if program == nil {
+			program, err = s.compileRule(rule)
+		}
+
+		if err != nil {
+			errors = append(errors, ValidationError{
+				Variable: rule.Variable,
+				Message:  fmt.Sprintf("invalid rule %q: %v", rule.Expr, err),
+			})
+			continue
+		}
+
+		names := identifiers(program)
+		env, ok := s.ruleEnv(vars, names)
+
+		if !ok {
+			continue
+		}
+
+		variable := rule.Variable
+		if variable == "" && len(names) > 0 {
+			variable = names[0]
+		}
+
+		output, err := expr.Run(program, env)
+
+		switch {
+		case err != nil:
+			errors = append(errors, ValidationError{
+				Variable: variable,
+				Message:  fmt.Sprintf("rule %q could not be evaluated: %v", rule.Expr, err),
+			})
+		case output != true:
+			errors = append(errors, ValidationError{Variable: variable, Message: rule.message()})
+		}
+	}
+
+	return errors
+}
+
+func (r Rule) message() string {
+	if r.Message != "" {
+		return r.Message
+	}
+
+	return fmt.Sprintf("rule %q is not satisfied", r.Expr)
+}
+
+func (s Schema) compileRule(rule Rule) (*vm.Program, error) {
+	types := make(map[string]any, len(s.Variables))
+
+	// json variables are left out so that they are typed as any.
+	for name, variable := range s.Variables {
+		if variable.Type != "json" {
+			types[name] = zeroValue(variable.Type)
+		}
+	}
+
+	return expr.Compile(rule.Expr, expr.Env(types), expr.AllowUndefinedVariables(), expr.AsBool())
+}
+
+// ruleEnv returns the typed values of vars, or false if one of the schema
+// variables in names is unset or invalid.
+func (s Schema) ruleEnv(vars map[string]string, names []string) (map[string]any, bool) {
+	env := make(map[string]any, len(vars))
+
+	for name, value := range vars {
+		variable, exists := s.Variables[name]
+
+		if !exists {
+			env[name] = value
+			continue
+		}
+
+		if value == "" || s.validateType(variable, value) != nil {
+			continue
+		}
+
+		env[name] = typedValue(variable.Type, value)
+	}
+
+	for _, name := range names {
+		if _, exists := s.Variables[name]; !exists {
+			continue
+		}
+
+		if _, set := env[name]; !set {
+			return nil, false
+		}
+	}
+
+	return env, true
+}
+
+// typedValue converts a value that is valid for typ.
+func typedValue(typ, value string) any {
+	switch typ {
+	case "integer", "port":
+		n, _ := strconv.Atoi(value)
+		return n
+	case "number":
+		n, _ := strconv.ParseFloat(value, 64)
+		return n
+	case "boolean":
+		switch value {
+		case "true", "1", "yes", "on":
+			return true
+		default:
+			return false
+		}
+	case "duration":
+		d, _ := time.ParseDuration(value)
+		return d
+	case "json":
+		var decoded any
+		_ = json.Unmarshal([]byte(value), &decoded)
+		return decoded
+	default:
+		return value
+	}
+}
+
+func zeroValue(typ string) any {
+	switch typ {
+	case "integer", "port":
+		return 0
+	case "number":
+		return 0.0
+	case "boolean":
+		return false
+	case "duration":
+		return time.Duration(0)
+	default:
+		return ""
+	}
+}
+
+// identifiers returns the names a program refers to, in order of first use.
+func identifiers(program *vm.Program) []string {
+	node := program.Node()
+	collector := &identifierCollector{seen: make(map[string]bool)}
+	ast.Walk(&node, collector)
+
+	return collector.names
+}
+
+type identifierCollector struct {
+	names []string
+	seen  map[string]bool
+}
+
+func (c *identifierCollector) Visit(node *ast.Node) {
+	if identifier, ok := (*node).(*ast.IdentifierNode); ok && !c.seen[identifier.Value] {
+		c.seen[identifier.Value] = true
+		c.names = append(c.names, identifier.Value)
+	}
+}
@@ -0,0 +1,130 @@
+package schema_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tommyalmeida/envsync/pkg/schema"
+)
+
+func TestSchema_ValidateRules(t *testing.T) {
+	t.Parallel()
+
+	s := schema.Schema{
+		Variables: map[string]schema.Variable{
+			"POOL_MIN": {Type: "integer"},
+			"POOL_MAX": {Type: "integer"},
+			"PORT":     {Type: "port"},
+			"TIMEOUT":  {Type: "duration"},
+			"DEBUG":    {Type: "boolean"},
+			"FEATURES": {Type: "json"},
+		},
+		Rules: []schema.Rule{
+			{Expr: "POOL_MAX >= POOL_MIN", Message: "POOL_MAX must be at least POOL_MIN"},
+			{Expr: "PORT not in 0..1023", Message: "privileged ports are not allowed"},
+			{Expr: `TIMEOUT <= duration("1m")`, Variable: "TIMEOUT"},
+			{Expr: "!DEBUG || REGION != 'prod'", Variable: "DEBUG", Message: "DEBUG must be off in prod"},
+			{Expr: "len(FEATURES) <= 2"},
+		},
+	}
+
+	if err := s.CompileRules(); err != nil {
+		t.Fatalf("CompileRules() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		vars     map[string]string
+		variable string
+		message  string
+	}{
+		{"valid", map[string]string{"POOL_MIN": "2", "POOL_MAX": "10", "PORT": "8080", "TIMEOUT": "30s", "DEBUG": "yes", "REGION": "dev", "FEATURES": "[1]"}, "", ""},
+		{"comparison", map[string]string{"POOL_MIN": "10", "POOL_MAX": "2"}, "POOL_MAX", "POOL_MAX must be at least POOL_MIN"},
+		{"range", map[string]string{"PORT": "443"}, "PORT", "privileged ports are not allowed"},
+		{"duration", map[string]string{"TIMEOUT": "5m"}, "TIMEOUT", `rule "TIMEOUT <= duration(\"1m\")" is not satisfied`},
+		{"boolean and extra variable", map[string]string{"DEBUG": "on", "REGION": "prod"}, "DEBUG", "DEBUG must be off in prod"},
+		{"json", map[string]string{"FEATURES": `{"a":1,"b":2,"c":3}`}, "FEATURES", "is not satisfied"},
+		{"unset variable skips the rule", map[string]string{"POOL_MIN": "10"}, "", ""},
+		{"invalid value skips the rule", map[string]string{"POOL_MIN": "10", "POOL_MAX": "many"}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := s.ValidateRules(tt.vars)
+
+			if tt.message == "" {
+				if len(errors) > 0 {
+					t.Errorf("expected no errors, got %v", errors)
+				}
+				return
+			}
+
+			if len(errors) != 1 || !errorContains(errors, tt.variable, tt.message) {
+				t.Errorf("expected one error %s: %q, got %v", tt.variable, tt.message, errors)
+			}
+		})
+	}
+}
+
+func TestSchema_CompileRules(t *testing.T) {
+	t.Parallel()
+
+	s := schema.Schema{
+		Variables: map[string]schema.Variable{"WORKERS": {Type: "integer"}},
+	}
+
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{"WORKERS > 0", ""},
+		{"WORKERS > ", "unexpected token"},
+		{"WORKERS + 'a' == 'b'", "mismatched types int and string"},
+		{"WORKERS", "expected bool"},
+	}
+
+	for _, tt := range tests {
+		s.Rules = []schema.Rule{{Expr: tt.expr}}
+		err := s.CompileRules()
+
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("CompileRules(%q) error = %v", tt.expr, err)
+			}
+			continue
+		}
+
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("CompileRules(%q) error = %v, want %q", tt.expr, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSchema_CompileRules_Copy(t *testing.T) {
+	t.Parallel()
+
+	s := schema.Schema{
+		Variables: map[string]schema.Variable{"WORKERS": {Type: "integer"}},
+		Rules:     []schema.Rule{{Expr: "WORKERS > 0"}},
+	}
+
+	if err := s.CompileRules(); err != nil {
+		t.Fatalf("CompileRules() error = %v", err)
+	}
+
+	// A copy with other types compiles its own rules.
+	override := s
+	override.Variables = map[string]schema.Variable{"WORKERS": {}}
+
+	if err := override.CompileRules(); err == nil {
+		t.Error("expected the rule not to compile for a string, got no error")
+	}
+
+	if errors := s.ValidateRules(map[string]string{"WORKERS": "0"}); len(errors) != 1 || !strings.Contains(errors[0].Message, "is not satisfied") {
+		t.Errorf("expected the original rule to fail, got %v", errors)
+	}
+
+	if errors := override.ValidateRules(map[string]string{"WORKERS": "0"}); len(errors) != 1 || !strings.Contains(errors[0].Message, "invalid rule") {
+		t.Errorf("expected the copy to report an invalid rule, got %v", errors)
+	}
+}
@@ -1,25 +1,150 @@
 package schema
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"net"
+	"net/url"
 	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Schema struct {
 	Variables map[string]Variable `yaml:"variables"`
+	// MutuallyExclusive lists groups of variables of which at most one may
+	// be set.
+	MutuallyExclusive [][]string `yaml:"mutually_exclusive,omitempty"`
+	// Rules are expressions every env file has to satisfy.
+	Rules []Rule `yaml:"rules,omitempty"`
 }
 
 type Variable struct {
-	Required    bool   `yaml:"required"`
-	Type        string `yaml:"type"` // string, number, boolean, url, email
-	Pattern     string `yaml:"pattern"`
-	Description string `yaml:"description"`
-	Default     string `yaml:"default"`
+	Required    bool   `yaml:"required,omitempty"`
+	Type        string `yaml:"type,omitempty"` // see Types
+	Pattern     string `yaml:"pattern,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	Default     string `yaml:"default,omitempty"`
+	// Secret masks the value in all output and keeps it out of validation
+	// messages.
+	Secret bool `yaml:"secret,omitempty"`
+
+	// Min and Max bound number, integer and port values.
+	Min *float64 `yaml:"min,omitempty"`
+	Max *float64 `yaml:"max,omitempty"`
+	// Values lists the allowed values of an enum.
+	Values []string `yaml:"values,omitempty"`
+	// Schemes restricts the schemes of a url, e.g. [https]. Any scheme is
+	// allowed when empty.
+	Schemes []string `yaml:"schemes,omitempty"`
+
+	// Requires lists variables that must be set when this one is.
+	Requires []string `yaml:"requires,omitempty"`
+	// ConflictsWith lists variables that must not be set when this one is.
+	ConflictsWith []string `yaml:"conflicts_with,omitempty"`
+	// RequiredIf makes the variable required when any of the listed
+	// variables has the given value.
+	RequiredIf map[string]string `yaml:"required_if,omitempty"`
+}
+
+// Types lists every supported variable type. An empty type means string.
+var Types = []string{
+	"string", "number", "integer", "boolean", "enum", "duration", "port",
+	"hostname", "ip", "cidr", "uuid", "json", "base64", "semver", "url", "email",
+}
+
+// inferredTypes are the types InferType tries, most specific first. Types
+// that almost any value satisfies, such as hostname and base64, are left out.
+var inferredTypes = []string{
+	"integer", "number", "boolean", "uuid", "ip", "cidr", "url", "email", "duration", "semver", "json",
+}
+
+// InferType returns the most specific type that every non-empty value
+// satisfies, or "string". Values that are all digits are never inferred to be
+// booleans, and only objects and arrays count as JSON.
+func InferType(values []string) string {
+	var samples []string
+
+	for _, value := range values {
+		if value != "" {
+			samples = append(samples, value)
+		}
+	}
+
+	if len(samples) == 0 {
+		return "string"
+	}
+
+	var s Schema
+
+	for _, candidate := range inferredTypes {
+		if !inferable(candidate, samples) {
+			continue
+		}
+
+		matches := true
+
+		for _, value := range samples {
+			if s.validateType(Variable{Type: candidate}, value) != nil {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			return candidate
+		}
+	}
+
+	return "string"
+}
+
+func inferable(candidate string, values []string) bool {
+	switch candidate {
+	case "boolean":
+		return slices.ContainsFunc(values, func(value string) bool {
+			_, err := strconv.Atoi(value)
+			return err != nil
+		})
+	case "json":
+		return !slices.ContainsFunc(values, func(value string) bool {
+			return !strings.HasPrefix(value, "{") && !strings.HasPrefix(value, "[")
+		})
+	default:
+		return true
+	}
 }
 
 type ValidationError struct {
 	Variable string `json:"variable"`
 	Message  string `json:"message"`
+	// Position is where the variable is assigned, when the values came from
+	// a file.
+	Position *Position `json:"position,omitempty"`
+}
+
+// Position is a place in an env file. Line and Column are 1-based.
+type Position struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line"`
+	Column int    `json:"column,omitempty"`
+}
+
+func (p Position) String() string {
+	s := strconv.Itoa(p.Line)
+
+	if p.Column > 0 {
+		s += ":" + strconv.Itoa(p.Column)
+	}
+
+	if p.File != "" {
+		s = p.File + ":" + s
+	}
+
+	return s
 }
 
 func (s Schema) ValidateVariable(name, value string) []ValidationError {
@@ -44,11 +169,24 @@ func (s Schema) ValidateVariable(name, value string) []ValidationError {
 		return errors
 	}
 
-	if err := s.validateType(variable.Type, value); err != nil {
+	if err := s.validateType(variable, value); err != nil {
+		if variable.Secret {
+			err = fmt.Errorf("secret value is not a valid %s", variable.Type)
+		}
+
 		errors = append(errors, ValidationError{
 			Variable: name,
 			Message:  fmt.Sprintf("type validation failed: %v", err),
 		})
+	} else if err := validateRange(variable, value); err != nil {
+		if variable.Secret {
+			err = fmt.Errorf("secret value is out of range")
+		}
+
+		errors = append(errors, ValidationError{
+			Variable: name,
+			Message:  err.Error(),
+		})
 	}
 
 	if variable.Pattern != "" {
@@ -68,29 +206,184 @@ func (s Schema) ValidateVariable(name, value string) []ValidationError {
 	return errors
 }
 
-func (s Schema) validateType(varType, value string) error {
-	switch varType {
+func (s Schema) validateType(variable Variable, value string) error {
+	switch variable.Type {
 	case "string", "":
 		return nil
 	case "number":
 		if matched, _ := regexp.MatchString(`^\d+(\.\d+)?$`, value); !matched {
 			return fmt.Errorf("not a valid number")
 		}
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("not a valid integer")
+		}
 	case "boolean":
 		if matched, _ := regexp.MatchString(`^(true|false|1|0|yes|no|on|off)$`, value); !matched {
 			return fmt.Errorf("not a valid boolean")
 		}
-	case "url":
-		if matched, _ := regexp.MatchString(`^https?://`, value); !matched {
-			return fmt.Errorf("not a valid URL")
+	case "enum":
+		return validateEnum(variable.Values, value)
+	case "duration":
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("not a valid duration, expected a value such as 30s, 5m or 1h30m")
+		}
+	case "port":
+		port, err := strconv.Atoi(value)
+
+		if err != nil {
+			return fmt.Errorf("not a valid port, expected an integer")
+		}
+
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("port %d is out of range 1-65535", port)
+		}
+	case "hostname":
+		return validateHostname(value)
+	case "ip":
+		if net.ParseIP(value) == nil {
+			return fmt.Errorf("not a valid IP address")
+		}
+	case "cidr":
+		if _, _, err := net.ParseCIDR(value); err != nil {
+			return fmt.Errorf("not a valid CIDR, expected an address and prefix length such as 10.0.0.0/8")
+		}
+	case "uuid":
+		if matched, _ := regexp.MatchString(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`, value); !matched {
+			return fmt.Errorf("not a valid UUID")
+		}
+	case "json":
+		if !json.Valid([]byte(value)) {
+			return fmt.Errorf("not valid JSON")
+		}
+	case "base64":
+		if !isBase64(value) {
+			return fmt.Errorf("not valid base64")
 		}
+	case "semver":
+		if !semverPattern.MatchString(value) {
+			return fmt.Errorf("not a valid semantic version, expected MAJOR.MINOR.PATCH")
+		}
+	case "url":
+		return validateURL(variable.Schemes, value)
 	case "email":
 		if matched, _ := regexp.MatchString(`^[^\s@]+@[^\s@]+\.[^\s@]+$`, value); !matched {
 			return fmt.Errorf("not a valid email")
 		}
 	default:
-		return fmt.Errorf("unknown type: %s", varType)
+		return fmt.Errorf("unknown type: %s", variable.Type)
 	}
 
 	return nil
 }
+
+// semverPattern is the pattern from semver.org with an optional "v" prefix.
+var semverPattern = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+func validateEnum(allowed []string, value string) error {
+	if len(allowed) == 0 {
+		return fmt.Errorf("enum type has no allowed values")
+	}
+
+	if slices.Contains(allowed, value) {
+		return nil
+	}
+
+	return fmt.Errorf("%q is not one of: %s", value, strings.Join(allowed, ", "))
+}
+
+// validateHostname checks value against RFC 1123.
+func validateHostname(value string) error {
+	if len(value) > 253 {
+		return fmt.Errorf("not a valid hostname, longer than 253 characters")
+	}
+
+	for _, label := range strings.Split(strings.TrimSuffix(value, "."), ".") {
+		switch {
+		case label == "":
+			return fmt.Errorf("not a valid hostname, empty label")
+		case len(label) > 63:
+			return fmt.Errorf("not a valid hostname, label %q is longer than 63 characters", label)
+		case strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-"):
+			return fmt.Errorf("not a valid hostname, label %q starts or ends with a hyphen", label)
+		}
+
+		for _, c := range label {
+			if !(c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')) {
+				return fmt.Errorf("not a valid hostname, label %q contains %q", label, c)
+			}
+		}
+	}
+
+	return nil
+}
+
+func isBase64(value string) bool {
+	for _, encoding := range []*base64.Encoding{
+		base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding,
+	} {
+		if _, err := encoding.DecodeString(value); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validateURL(schemes []string, value string) error {
+	u, err := url.Parse(value)
+
+	if err != nil {
+		return fmt.Errorf("not a valid URL")
+	}
+
+	if u.Scheme == "" {
+		return fmt.Errorf("not a valid URL, missing scheme such as https://")
+	}
+
+	if u.Host == "" {
+		return fmt.Errorf("not a valid URL, missing host")
+	}
+
+	if len(schemes) > 0 && !slices.Contains(schemes, u.Scheme) {
+		return fmt.Errorf("URL scheme %q is not one of: %s", u.Scheme, strings.Join(schemes, ", "))
+	}
+
+	return nil
+}
+
+// validateRange checks min and max for types with numeric values. It assumes
+// the value already passed type validation.
+func validateRange(variable Variable, value string) error {
+	if variable.Min == nil && variable.Max == nil {
+		return nil
+	}
+
+	switch variable.Type {
+	case "number", "integer", "port":
+	default:
+		return nil
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+
+	if err != nil {
+		return nil
+	}
+
+	if variable.Min != nil && number < *variable.Min {
+		return fmt.Errorf("value %s is less than the minimum %s", value, formatBound(*variable.Min))
+	}
+
+	if variable.Max != nil && number > *variable.Max {
+		return fmt.Errorf("value %s is greater than the maximum %s", value, formatBound(*variable.Max))
+	}
+
+	return nil
+}
+
+func formatBound(bound float64) string {
+	return strconv.FormatFloat(bound, 'f', -1, 64)
+}
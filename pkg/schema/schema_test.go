@@ -65,6 +65,207 @@ func TestSchema_ValidateVariable(t *testing.T) {
 	}
 }
 
+func TestSchema_ValidateVariable_RichTypes(t *testing.T) {
+	t.Parallel()
+
+	minPort, maxPort := 1024.0, 9000.0
+	minWorkers, maxWorkers := 1.0, 16.0
+
+	schema := schema.Schema{
+		Variables: map[string]schema.Variable{
+			"WORKERS":   {Type: "integer", Min: &minWorkers, Max: &maxWorkers},
+			"RATIO":     {Type: "number", Max: &minWorkers},
+			"LOG_LEVEL": {Type: "enum", Values: []string{"debug", "info", "warn"}},
+			"NO_VALUES": {Type: "enum"},
+			"TIMEOUT":   {Type: "duration"},
+			"PORT":      {Type: "port"},
+			"APP_PORT":  {Type: "port", Min: &minPort, Max: &maxPort},
+			"HOST":      {Type: "hostname"},
+			"BIND_IP":   {Type: "ip"},
+			"SUBNET":    {Type: "cidr"},
+			"REQUEST":   {Type: "uuid"},
+			"SETTINGS":  {Type: "json"},
+			"CERT":      {Type: "base64"},
+			"VERSION":   {Type: "semver"},
+			"DB_URL":    {Type: "url"},
+			"API_URL":   {Type: "url", Schemes: []string{"https"}},
+			"STRANGE":   {Type: "colour"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		variable string
+		value    string
+		wantErr  bool
+		errMsg   string
+	}{
+		{"valid integer", "WORKERS", "4", false, ""},
+		{"decimal integer", "WORKERS", "4.5", true, "not a valid integer"},
+		{"integer below min", "WORKERS", "0", true, "value 0 is less than the minimum 1"},
+		{"integer above max", "WORKERS", "32", true, "value 32 is greater than the maximum 16"},
+		{"number above max", "RATIO", "1.5", true, "greater than the maximum 1"},
+		{"valid enum", "LOG_LEVEL", "info", false, ""},
+		{"invalid enum", "LOG_LEVEL", "trace", true, `"trace" is not one of: debug, info, warn`},
+		{"enum without values", "NO_VALUES", "x", true, "enum type has no allowed values"},
+		{"valid duration", "TIMEOUT", "1h30m", false, ""},
+		{"invalid duration", "TIMEOUT", "30", true, "not a valid duration"},
+		{"valid port", "PORT", "8080", false, ""},
+		{"port out of range", "PORT", "70000", true, "port 70000 is out of range 1-65535"},
+		{"port not a number", "PORT", "http", true, "not a valid port"},
+		{"port below min", "APP_PORT", "80", true, "value 80 is less than the minimum 1024"},
+		{"valid hostname", "HOST", "db-1.internal.example.com", false, ""},
+		{"hostname with underscore", "HOST", "db_1.example.com", true, `label "db_1" contains '_'`},
+		{"hostname label hyphen", "HOST", "-db.example.com", true, "starts or ends with a hyphen"},
+		{"hostname empty label", "HOST", "db..example.com", true, "empty label"},
+		{"valid ipv4", "BIND_IP", "10.0.0.1", false, ""},
+		{"valid ipv6", "BIND_IP", "::1", false, ""},
+		{"invalid ip", "BIND_IP", "10.0.0.256", true, "not a valid IP address"},
+		{"valid cidr", "SUBNET", "10.0.0.0/8", false, ""},
+		{"invalid cidr", "SUBNET", "10.0.0.0", true, "not a valid CIDR"},
+		{"valid uuid", "REQUEST", "123e4567-e89b-12d3-a456-426614174000", false, ""},
+		{"invalid uuid", "REQUEST", "123e4567", true, "not a valid UUID"},
+		{"valid json", "SETTINGS", `{"a":[1,2]}`, false, ""},
+		{"invalid json", "SETTINGS", `{"a":`, true, "not valid JSON"},
+		{"valid base64", "CERT", "aGVsbG8=", false, ""},
+		{"valid raw url base64", "CERT", "aGVsbG8_-w", false, ""},
+		{"invalid base64", "CERT", "not base64!", true, "not valid base64"},
+		{"valid semver", "VERSION", "1.2.3-rc.1+build.5", false, ""},
+		{"valid semver prefix", "VERSION", "v2.0.0", false, ""},
+		{"invalid semver", "VERSION", "1.2", true, "not a valid semantic version"},
+		{"semver leading zero", "VERSION", "01.2.3", true, "not a valid semantic version"},
+		{"valid url any scheme", "DB_URL", "postgres://user@db:5432/app", false, ""},
+		{"url without host", "DB_URL", "https://", true, "missing host"},
+		{"url without scheme", "DB_URL", "example.com/path", true, "missing scheme"},
+		{"url allowed scheme", "API_URL", "https://api.example.com", false, ""},
+		{"url disallowed scheme", "API_URL", "http://api.example.com", true, `URL scheme "http" is not one of: https`},
+		{"unknown type", "STRANGE", "red", true, "unknown type: colour"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			errors := schema.ValidateVariable(tt.variable, tt.value)
+
+			if tt.wantErr {
+				if !errorContains(errors, tt.variable, tt.errMsg) {
+					t.Errorf("expected error message to contain '%s', got: %v", tt.errMsg, errors)
+				}
+			} else if len(errors) > 0 {
+				t.Errorf("expected no validation error, got: %v", errors)
+			}
+		})
+	}
+}
+
+func TestSchema_ValidateVariable_SecretRedacted(t *testing.T) {
+	t.Parallel()
+
+	maxPort := 9000.0
+
+	schema := schema.Schema{
+		Variables: map[string]schema.Variable{
+			"MODE":     {Type: "enum", Values: []string{"a", "b"}, Secret: true},
+			"API_PORT": {Type: "port", Max: &maxPort, Secret: true},
+		},
+	}
+
+	for name, value := range map[string]string{"MODE": "hunter2", "API_PORT": "9999"} {
+		errors := schema.ValidateVariable(name, value)
+
+		if len(errors) == 0 {
+			t.Fatalf("%s: expected validation error, got none", name)
+		}
+
+		for _, err := range errors {
+			if strings.Contains(err.Message, value) {
+				t.Errorf("%s: message %q contains the secret value", name, err.Message)
+			}
+		}
+	}
+}
+
+func TestInferType(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		values []string
+		want   string
+	}{
+		{[]string{"1", "42"}, "integer"},
+		{[]string{"1", "1.5"}, "number"},
+		{[]string{"true", "0"}, "boolean"},
+		{[]string{"0", "1"}, "integer"},
+		{[]string{"30s", "1h"}, "duration"},
+		{[]string{"10.0.0.1", "::1"}, "ip"},
+		{[]string{"https://example.com"}, "url"},
+		{[]string{"1.2.3", "v2.0.0-rc.1"}, "semver"},
+		{[]string{`{"a":1}`}, "json"},
+		{[]string{"42", "forty-two"}, "string"},
+		{[]string{"", "8080"}, "integer"},
+		{[]string{""}, "string"},
+	}
+
+	for _, tt := range tests {
+		if got := schema.InferType(tt.values); got != tt.want {
+			t.Errorf("InferType(%q) = %s, want %s", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestSchema_ValidateConstraints(t *testing.T) {
+	t.Parallel()
+
+	s := schema.Schema{
+		Variables: map[string]schema.Variable{
+			"TLS_CERT_PATH": {Requires: []string{"TLS_KEY_PATH"}, RequiredIf: map[string]string{"TLS_ENABLED": "true"}},
+			"TLS_KEY_PATH":  {},
+			"TLS_ENABLED":   {Type: "boolean"},
+			"DATABASE_URL":  {ConflictsWith: []string{"DB_HOST"}},
+			"DB_HOST":       {},
+			"API_KEY":       {ConflictsWith: []string{"API_TOKEN"}},
+			"API_TOKEN":     {ConflictsWith: []string{"API_KEY"}},
+			"REDIS_URL":     {},
+			"MEMCACHED_URL": {},
+		},
+		MutuallyExclusive: [][]string{{"REDIS_URL", "MEMCACHED_URL"}},
+	}
+
+	tests := []struct {
+		name     string
+		vars     map[string]string
+		variable string
+		message  string
+	}{
+		{"valid", map[string]string{"TLS_ENABLED": "true", "TLS_CERT_PATH": "c", "TLS_KEY_PATH": "k", "DATABASE_URL": "x"}, "", ""},
+		{"requires", map[string]string{"TLS_CERT_PATH": "c"}, "TLS_CERT_PATH", "requires TLS_KEY_PATH"},
+		{"requires empty", map[string]string{"TLS_CERT_PATH": "c", "TLS_KEY_PATH": ""}, "TLS_CERT_PATH", "requires TLS_KEY_PATH"},
+		{"conflicts", map[string]string{"DATABASE_URL": "x", "DB_HOST": "h"}, "DATABASE_URL", "conflicts with DB_HOST"},
+		{"conflicts both ways", map[string]string{"API_KEY": "k", "API_TOKEN": "t"}, "API_KEY", "conflicts with API_TOKEN"},
+		{"required if", map[string]string{"TLS_ENABLED": "true"}, "TLS_CERT_PATH", `required because TLS_ENABLED is "true"`},
+		{"required if other value", map[string]string{"TLS_ENABLED": "false"}, "", ""},
+		{"mutually exclusive", map[string]string{"REDIS_URL": "r", "MEMCACHED_URL": "m"}, "REDIS_URL", "only one of REDIS_URL, MEMCACHED_URL may be set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := s.ValidateConstraints(tt.vars)
+
+			if tt.message == "" {
+				if len(errors) > 0 {
+					t.Errorf("expected no errors, got %v", errors)
+				}
+				return
+			}
+
+			if len(errors) != 1 || !errorContains(errors, tt.variable, tt.message) {
+				t.Errorf("expected one error %s: %q, got %v", tt.variable, tt.message, errors)
+			}
+		})
+	}
+}
+
 func errorContains(errors []schema.ValidationError, variable, substr string) bool {
 	for _, err := range errors {
 		if err.Variable == variable && strings.Contains(err.Message, substr) {